@@ -0,0 +1,89 @@
+package formulae
+
+// mergeParams returns the parameters of f followed by the parameters of g that are not parameters of f.
+func mergeParams(f, g *Function) []string {
+	if f.params == nil && g.params == nil {
+		return nil
+	}
+	params := append([]string{}, f.Params()...)
+	for _, name := range g.Params() {
+		found := false
+		for _, param := range params {
+			if param == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			params = append(params, name)
+		}
+	}
+	return params
+}
+
+// sameDerivative returns whether f and g are derivatives or antiderivatives of the same order to the same variables.
+func sameDerivative(f, g *Function) bool {
+	if f.nthDerivative != g.nthDerivative || len(f.wrt) != len(g.wrt) {
+		return false
+	}
+	for i := range f.wrt {
+		if f.wrt[i] != g.wrt[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// binary returns the function f op g. Since differentiation and integration are linear, the result of an addition or subtraction of derivatives of the same order to the same variables is still such a derivative.
+func (f *Function) binary(op Operator, g *Function) (*Function, error) {
+	vars, err := mergeVars(f.Vars, g.Vars)
+	if err != nil {
+		return nil, err
+	}
+	h := &Function{
+		root:   &Expr{op: op, l: f.root, r: g.root},
+		Vars:   vars,
+		params: mergeParams(f, g),
+	}
+	if (op == AddOp || op == SubtractOp) && sameDerivative(f, g) {
+		h.nthDerivative = f.nthDerivative
+		h.wrt = f.wrt
+	}
+	return h, nil
+}
+
+// Add returns f+g. The variables of f and g are merged, which returns an error if a variable has different values in both, and the parameters of g that are not parameters of f are appended.
+func (f *Function) Add(g *Function) (*Function, error) {
+	return f.binary(AddOp, g)
+}
+
+// Sub returns f-g, see Add.
+func (f *Function) Sub(g *Function) (*Function, error) {
+	return f.binary(SubtractOp, g)
+}
+
+// Mul returns f*g, see Add.
+func (f *Function) Mul(g *Function) (*Function, error) {
+	return f.binary(MultiplyOp, g)
+}
+
+// Div returns f/g, see Add.
+func (f *Function) Div(g *Function) (*Function, error) {
+	return f.binary(DivideOp, g)
+}
+
+// Pow returns f^g, see Add.
+func (f *Function) Pow(g *Function) (*Function, error) {
+	return f.binary(PowerOp, g)
+}
+
+// Neg returns -f, which is a derivative or antiderivative of the same order as f.
+func (f *Function) Neg() *Function {
+	return &Function{
+		root:          &UnaryExpr{op: MinusOp, a: f.root},
+		Vars:          f.Vars,
+		nthDerivative: f.nthDerivative,
+		wrt:           f.wrt,
+		params:        f.params,
+	}
+}
@@ -0,0 +1,128 @@
+package formulae
+
+import "testing"
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		op   string
+		f, g string
+		out  string
+	}{
+		{"+", "sin(x)", "a*x", "sin(x)+a*x"},
+		{"-", "x", "y+1", "x-(y+1)"},
+		{"*", "x+1", "x-1", "(x+1)*(x-1)"},
+		{"/", "1", "x*y", "1/(x*y)"},
+		{"^", "x+1", "2", "(x+1)^2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.f+test.op+test.g, func(t *testing.T) {
+			f, errs := Parse(test.f)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			g, errs := Parse(test.g)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			var h *Function
+			var err error
+			switch test.op {
+			case "+":
+				h, err = f.Add(g)
+			case "-":
+				h, err = f.Sub(g)
+			case "*":
+				h, err = f.Mul(g)
+			case "/":
+				h, err = f.Div(g)
+			case "^":
+				h, err = f.Pow(g)
+			}
+			if err != nil {
+				t.Fatal(err)
+			} else if h.String() != test.out {
+				t.Fatal(h, "!=", test.out)
+			}
+
+			// must be equal to the parsed formula
+			want, errs := Parse(test.out)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			want.Vars.Set("a", 2.0)
+			want.Vars.Set("y", 3.0)
+			h.Vars.Set("a", 2.0)
+			h.Vars.Set("y", 3.0)
+			y, err := h.Calc(0.5)
+			if err != nil {
+				t.Fatal(err)
+			} else if y2, _ := want.Calc(0.5); y != y2 {
+				t.Fatal(y, "!=", y2)
+			}
+		})
+	}
+}
+
+func TestArithmeticVars(t *testing.T) {
+	f, errs := Parse("a*x")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f.Vars.Set("a", 2.0)
+	g, errs := Parse("b")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	g.Vars.Set("b", 3.0)
+	g.SetParams("b")
+
+	h, err := f.Add(g)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.Vars["a"] != 2.0 || h.Vars["b"] != 3.0 {
+		t.Fatal(h.Vars, "does not contain a and b")
+	} else if len(h.Params()) != 2 || h.Params()[1] != "b" {
+		t.Fatal(h.Params(), "!=", []string{"x", "b"})
+	}
+
+	g.Vars.Set("a", 1.0)
+	if _, err := f.Mul(g); err == nil {
+		t.Fatal("expected conflicting variable error")
+	}
+}
+
+func TestArithmeticDerivative(t *testing.T) {
+	f, errs := Parse("x^2")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	g, errs := Parse("sin(x)")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	df, dg := f.Derivative(), g.Derivative()
+
+	h, err := df.Add(dg)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.LaTeX() != "\\frac{\\partial}{\\partial x} f(x) = 2 x+\\cos x" {
+		t.Fatal(h.LaTeX(), "!=", "\\frac{\\partial}{\\partial x} f(x) = 2 x+\\cos x")
+	} else if h := df.Neg(); h.nthDerivative != 1 {
+		t.Fatal(h.nthDerivative, "!=", 1)
+	}
+
+	h, err = df.Mul(dg)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.nthDerivative != 0 {
+		t.Fatal(h.nthDerivative, "!=", 0)
+	}
+	h, err = df.Add(g)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.nthDerivative != 0 {
+		t.Fatal(h.nthDerivative, "!=", 0)
+	}
+}
@@ -0,0 +1,153 @@
+package formulae
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tdewolff/formulae/hash"
+)
+
+// NewFunction returns a function with root as its expression and the default variables, such as e and pi.
+func NewFunction(root Node) *Function {
+	return &Function{root: root, Vars: DefaultVars.Duplicate()}
+}
+
+// Root returns the root node of the expression of f.
+func (f *Function) Root() Node {
+	return f.root
+}
+
+// NewNumber returns a number node.
+func NewNumber(val complex128) *Number {
+	return &Number{val: val}
+}
+
+// NewVariable returns a variable node.
+func NewVariable(name string) *Variable {
+	return &Variable{name: name, pos: -1}
+}
+
+// NewExpr returns a binary expression node. It returns an error if op is not one of AddOp, SubtractOp, MultiplyOp, DivideOp, ModOp or PowerOp, or a comparison or boolean operator.
+func NewExpr(op Operator, l, r Node) (*Expr, error) {
+	switch op {
+	case AddOp, SubtractOp, MultiplyOp, DivideOp, ModOp, PowerOp, LessOp, LessEqualOp, GreaterOp, GreaterEqualOp, EqualOp, NotEqualOp, AndOp, OrOp:
+		return &Expr{op: op, l: l, r: r}, nil
+	}
+	return nil, fmt.Errorf("invalid binary operator '%s'", op)
+}
+
+// NewUnaryExpr returns a unary expression node. It returns an error if op is not MinusOp, NotOp or FactorialOp.
+func NewUnaryExpr(op Operator, a Node) (*UnaryExpr, error) {
+	switch op {
+	case MinusOp, NotOp, FactorialOp:
+		return &UnaryExpr{op: op, a: a}, nil
+	}
+	return nil, fmt.Errorf("invalid unary operator '%s'", op)
+}
+
+// NewFunc returns a function node, such as sin or atan2, in the same way as the parser does. It returns an error for unknown functions and the wrong number of arguments. Like for the parser, ln is the same as log, exp and pow return a power expression, and piecewise returns a Piecewise node.
+func NewFunc(name string, args ...Node) (Node, error) {
+	h := hash.ToHash([]byte(strings.ToLower(name)))
+	if h == 0 {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+	return newFuncNode(h, append([]Node{}, args...))
+}
+
+// Value returns the value of the number.
+func (n *Number) Value() complex128 {
+	return n.val
+}
+
+// Name returns the name of the variable.
+func (n *Variable) Name() string {
+	return n.name
+}
+
+// Pos returns the offset of the variable in the parsed input, or -1 if it was not parsed.
+func (n *Variable) Pos() int {
+	return n.pos
+}
+
+// Op returns the operator of the expression.
+func (n *Expr) Op() Operator {
+	return n.op
+}
+
+// Left returns the left operand of the expression.
+func (n *Expr) Left() Node {
+	return n.l
+}
+
+// Right returns the right operand of the expression.
+func (n *Expr) Right() Node {
+	return n.r
+}
+
+// Op returns the operator of the unary expression.
+func (n *UnaryExpr) Op() Operator {
+	return n.op
+}
+
+// Operand returns the operand of the unary expression.
+func (n *UnaryExpr) Operand() Node {
+	return n.a
+}
+
+// Name returns the name of the function, such as sin.
+func (n *Func) Name() string {
+	return n.name.String()
+}
+
+// Args returns the arguments of the function.
+func (n *Func) Args() []Node {
+	return append([]Node{}, n.args...)
+}
+
+////////////////
+
+// Visitor's Visit method is invoked for each node encountered by Walk. If the result visitor w is not nil, Walk visits each of the operands of n with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(n Node) (w Visitor)
+}
+
+// Walk traverses an expression in depth-first order: it starts by calling v.Visit(n); n must not be nil. If the visitor w returned by v.Visit(n) is not nil, Walk is invoked recursively with visitor w for each of the operands of n, followed by a call of w.Visit(nil).
+func Walk(v Visitor, n Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+	for _, c := range children(n) {
+		Walk(v, c)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(n Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an expression in depth-first order: it starts by calling f(n); n must not be nil. If f returns true, Inspect invokes f recursively for each of the operands of n, followed by a call of f(nil).
+func Inspect(n Node, f func(Node) bool) {
+	Walk(inspector(f), n)
+}
+
+// Rewrite returns a copy of an expression where each node is replaced by the result of f, in depth-first order so that f receives a node whose operands have already been rewritten. It does not modify n, and unchanged subexpressions are shared with n.
+func Rewrite(n Node, f func(Node) Node) Node {
+	if cs := children(n); cs != nil {
+		args := make([]Node, len(cs))
+		changed := false
+		for i, c := range cs {
+			args[i] = Rewrite(c, f)
+			changed = changed || args[i] != c
+		}
+		if changed {
+			n = withChildren(n, args)
+		}
+	}
+	return f(n)
+}
@@ -0,0 +1,170 @@
+package formulae
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		args []Node
+		out  string
+		err  string
+	}{
+		{"sin", []Node{NewVariable("x")}, "sin(x)", ""},
+		{"Atan2", []Node{NewVariable("y"), NewNumber(1.0)}, "atan2(y,1)", ""},
+		{"ln", []Node{NewVariable("x")}, "log(x)", ""},
+		{"exp", []Node{NewVariable("x")}, "e^x", ""},
+		{"pow", []Node{NewVariable("x"), NewNumber(2.0)}, "x^2", ""},
+		{"sin", nil, "", "function 'sin' takes 1 argument, got 0"},
+		{"foo", []Node{NewVariable("x")}, "", "unknown function 'foo'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := NewFunc(test.name, test.args...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatal(err, "!=", test.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if n.String() != test.out {
+				t.Fatal(n, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	sin, err := NewFunc("sin", NewVariable("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pow, err := NewExpr(PowerOp, sin, NewNumber(2.0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	neg, err := NewUnaryExpr(MinusOp, NewVariable("pi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewExpr(AddOp, pow, neg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFunction(root)
+	if f.String() != "sin(x)^2+-pi" {
+		t.Fatal(f, "!=", "sin(x)^2+-pi")
+	} else if f.Root() != root {
+		t.Fatal(f.Root(), "!=", root)
+	}
+
+	y, err := f.Calc(0.0)
+	if err != nil {
+		t.Fatal(err)
+	} else if y != -3.141592653589793 {
+		t.Fatal(y, "!=", -3.141592653589793)
+	}
+}
+
+func TestConstructorsErr(t *testing.T) {
+	if _, err := NewExpr(OpenOp, NewNumber(1.0), NewNumber(2.0)); err == nil || err.Error() != "invalid binary operator '('" {
+		t.Fatal(err, "!=", "invalid binary operator '('")
+	}
+	if _, err := NewUnaryExpr(AddOp, NewVariable("x")); err == nil || err.Error() != "invalid unary operator '+'" {
+		t.Fatal(err, "!=", "invalid unary operator '+'")
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	f, errs := Parse("atan2(-y, 2)*x")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	mul, ok := f.Root().(*Expr)
+	if !ok || mul.Op() != MultiplyOp {
+		t.Fatal(f.Root(), "is not a multiplication")
+	} else if x, ok := mul.Right().(*Variable); !ok || x.Name() != "x" {
+		t.Fatal(mul.Right(), "!=", "x")
+	}
+
+	atan2, ok := mul.Left().(*Func)
+	if !ok || atan2.Name() != "atan2" || len(atan2.Args()) != 2 {
+		t.Fatal(mul.Left(), "!=", "atan2(-y,2)")
+	} else if neg, ok := atan2.Args()[0].(*UnaryExpr); !ok || neg.Op() != MinusOp || neg.Operand().String() != "y" {
+		t.Fatal(atan2.Args()[0], "!=", "-y")
+	} else if two, ok := atan2.Args()[1].(*Number); !ok || two.Value() != 2.0 {
+		t.Fatal(atan2.Args()[1], "!=", "2")
+	}
+}
+
+type recorder struct {
+	sb *strings.Builder
+}
+
+func (v recorder) Visit(n Node) Visitor {
+	if n == nil {
+		v.sb.WriteString(")")
+		return nil
+	}
+	v.sb.WriteString("(" + n.String())
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	f, errs := Parse("sin(x)+2")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	sb := &strings.Builder{}
+	Walk(recorder{sb}, f.Root())
+	if want := "(sin(x)+2(sin(x)(x))(2))"; sb.String() != want {
+		t.Fatal(sb.String(), "!=", want)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	f, errs := Parse("x*y+sin(x*z)")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	names := []string{}
+	Inspect(f.Root(), func(n Node) bool {
+		if v, ok := n.(*Variable); ok {
+			names = append(names, v.Name())
+		}
+		_, isFunc := n.(*Func)
+		return !isFunc
+	})
+	if strings.Join(names, ",") != "x,y" {
+		t.Fatal(names, "!=", []string{"x", "y"})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	f, errs := Parse("x*y+sin(x)")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	root := Rewrite(f.Root(), func(n Node) Node {
+		if v, ok := n.(*Variable); ok && v.Name() == "x" {
+			return NewNumber(2.0)
+		}
+		return n
+	})
+	if root.String() != "2*y+sin(2)" {
+		t.Fatal(root, "!=", "2*y+sin(2)")
+	} else if f.String() != "x*y+sin(x)" {
+		t.Fatal(f, "!=", "x*y+sin(x)")
+	}
+
+	if Rewrite(f.Root(), func(n Node) Node { return n }) != f.Root() {
+		t.Fatal("identity rewrite must return the same tree")
+	}
+}
@@ -0,0 +1,142 @@
+package formulae
+
+import (
+	"sort"
+	"strings"
+)
+
+// nodeRank orders the kinds of nodes: numbers, variables, functions, piecewise functions, unary expressions and binary expressions.
+func nodeRank(n Node) int {
+	switch n.(type) {
+	case *Number:
+		return 0
+	case *Variable:
+		return 1
+	case *Func:
+		return 2
+	case *Piecewise:
+		return 3
+	case *UnaryExpr:
+		return 4
+	case *Expr:
+		return 5
+	}
+	return 6
+}
+
+// compareNodes returns -1, 0 or 1 if a orders before, equal to or after b in the canonical order.
+func compareNodes(a, b Node) int {
+	if ra, rb := nodeRank(a), nodeRank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch na := a.(type) {
+	case *Number:
+		nb := b.(*Number)
+		if real(na.val) != real(nb.val) {
+			if real(na.val) < real(nb.val) {
+				return -1
+			}
+			return 1
+		} else if imag(na.val) != imag(nb.val) {
+			if imag(na.val) < imag(nb.val) {
+				return -1
+			}
+			return 1
+		}
+	case *Variable:
+		return strings.Compare(na.name, b.(*Variable).name)
+	case *Func:
+		nb := b.(*Func)
+		if c := strings.Compare(na.name.String(), nb.name.String()); c != 0 {
+			return c
+		} else if len(na.args) != len(nb.args) {
+			if len(na.args) < len(nb.args) {
+				return -1
+			}
+			return 1
+		}
+		for i := range na.args {
+			if c := compareNodes(na.args[i], nb.args[i]); c != 0 {
+				return c
+			}
+		}
+	case *Piecewise:
+		nb := b.(*Piecewise)
+		if len(na.args) != len(nb.args) {
+			if len(na.args) < len(nb.args) {
+				return -1
+			}
+			return 1
+		}
+		for i := range na.args {
+			if c := compareNodes(na.args[i], nb.args[i]); c != 0 {
+				return c
+			}
+		}
+	case *UnaryExpr:
+		nb := b.(*UnaryExpr)
+		if na.op != nb.op {
+			if na.op < nb.op {
+				return -1
+			}
+			return 1
+		}
+		return compareNodes(na.a, nb.a)
+	case *Expr:
+		nb := b.(*Expr)
+		if na.op != nb.op {
+			if na.op < nb.op {
+				return -1
+			}
+			return 1
+		} else if c := compareNodes(na.l, nb.l); c != 0 {
+			return c
+		}
+		return compareNodes(na.r, nb.r)
+	}
+	return 0
+}
+
+// Canonical returns a copy of n in canonical form, where chains of additions and multiplications are flattened and their operands are sorted. Numbers come first, followed by variables, functions and other expressions. The result is structurally equal for expressions that only differ in the order or grouping of their operands, such as x+y and y+x or 2*(x*y) and (y*x)*2.
+func Canonical(in Node) Node {
+	cs := children(in)
+	if cs == nil {
+		return in
+	}
+	args := make([]Node, len(cs))
+	for i, c := range cs {
+		args[i] = Canonical(c)
+	}
+	out := withChildren(in, args)
+
+	if n, ok := out.(*Expr); ok && (n.op == AddOp || n.op == MultiplyOp) {
+		ns := operands(n, n.op, nil)
+		sort.SliceStable(ns, func(i, j int) bool {
+			return compareNodes(ns[i], ns[j]) < 0
+		})
+		return joinOperands(n.op, ns)
+	}
+	return out
+}
+
+// Equivalent returns whether a and b are equal up to the order and grouping of the operands of additions and multiplications.
+func Equivalent(a, b Node) bool {
+	if a.Equal(b) {
+		return true
+	}
+	return Canonical(a).Equal(Canonical(b))
+}
+
+// Canonicalize puts f in canonical form, see Canonical.
+func (f *Function) Canonicalize() {
+	f.root = Canonical(f.root)
+}
+
+// Equivalent returns whether f and g are equal up to the order and grouping of the operands of additions and multiplications.
+func (f *Function) Equivalent(g *Function) bool {
+	return Equivalent(f.root, g.root)
+}
@@ -0,0 +1,67 @@
+package formulae
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"y+x", "x+y"},
+		{"x*2", "2*x"},
+		{"(c+b)+a", "a+b+c"},
+		{"c+(b+a)", "a+b+c"},
+		{"(y*x)*2", "2*x*y"},
+		{"sin(x)+x+1", "1+x+sin(x)"},
+		{"sin(y+x)*cos(b*a)", "cos(a*b)*sin(x+y)"},
+		{"y-x", "y-x"},
+		{"(b+a)-(d*c)", "a+b-c*d"},
+		{"x^(b+a)", "x^(a+b)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Canonicalize()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		equivalent bool
+	}{
+		{"x+y", "y+x", true},
+		{"2*x", "x*2", true},
+		{"2*(x*y)", "(y*x)*2", true},
+		{"a+b+c", "c+(a+b)", true},
+		{"sin(x+y)", "sin(y+x)", true},
+		{"x-y", "y-x", false},
+		{"x/y", "y/x", false},
+		{"x+y", "x*y", false},
+		{"x+y", "x+y+0", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+"="+test.b, func(t *testing.T) {
+			f, errs := Parse(test.a)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			g, errs := Parse(test.b)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if f.Equivalent(g) != test.equivalent {
+				t.Fatal(f.Equivalent(g), "!=", test.equivalent)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package formulae
+
+import (
+	"fmt"
+	"math/cmplx"
+
+	"github.com/tdewolff/formulae/hash"
+)
+
+type instrKind int
+
+const (
+	exprInstr instrKind = iota
+	unaryInstr
+	funcInstr
+	moveInstr     // copies register a to dst
+	jumpInstr     // continues at target
+	jumpZeroInstr // continues at target if register a is zero
+	failInstr     // fails because none of the conditions of a piecewise function hold
+)
+
+// instr is a single instruction of a Program that writes the result of an operation on the registers a and b, or on the registers args, to the register dst.
+type instr struct {
+	kind   instrKind
+	op     Operator
+	name   hash.Hash
+	dst    int
+	a, b   int
+	args   []int
+	target int
+}
+
+// Program is a Function compiled to a flat list of register instructions. Variables are resolved to registers at compile time, so that evaluation does not walk the node tree, look up variables, or allocate memory. A Program is not safe for concurrent use, but each goroutine may compile its own.
+type Program struct {
+	code    []instr
+	regs    []complex128
+	scratch []complex128 // arguments of function instructions
+	vars    map[string]int
+	params  []int
+	out     int
+}
+
+// Compile compiles f to a Program whose arguments are bound to the parameters of f. It returns an error for variables that are neither parameters nor defined in the variables of f.
+func (f *Function) Compile() (*Program, error) {
+	return f.compile(f.Params())
+}
+
+func (f *Function) compile(params []string) (*Program, error) {
+	p := &Program{
+		vars: map[string]int{},
+	}
+	for _, name := range params {
+		p.params = append(p.params, p.variable(name))
+	}
+
+	out, err := p.compile(f.root, f.Vars)
+	if err != nil {
+		return nil, err
+	}
+	p.out = out
+	return p, nil
+}
+
+// variable returns the register of a variable, adding it when needed.
+func (p *Program) variable(name string) int {
+	if i, ok := p.vars[name]; ok {
+		return i
+	}
+	p.regs = append(p.regs, 0)
+	p.vars[name] = len(p.regs) - 1
+	return len(p.regs) - 1
+}
+
+func (p *Program) compile(in Node, vars Vars) (int, error) {
+	switch n := in.(type) {
+	case *Number:
+		p.regs = append(p.regs, n.val)
+		return len(p.regs) - 1, nil
+	case *Variable:
+		if i, ok := p.vars[n.name]; ok {
+			return i, nil
+		} else if val, ok := vars[n.name]; ok {
+			i := p.variable(n.name)
+			p.regs[i] = val
+			return i, nil
+		}
+		return 0, fmt.Errorf("undefined variable '%s'", n.name)
+	case *Expr:
+		a, err := p.compile(n.l, vars)
+		if err != nil {
+			return 0, err
+		}
+		b, err := p.compile(n.r, vars)
+		if err != nil {
+			return 0, err
+		}
+		return p.emit(instr{kind: exprInstr, op: n.op, a: a, b: b}), nil
+	case *UnaryExpr:
+		a, err := p.compile(n.a, vars)
+		if err != nil {
+			return 0, err
+		}
+		return p.emit(instr{kind: unaryInstr, op: n.op, a: a}), nil
+	case *Func:
+		args := make([]int, len(n.args))
+		for i, arg := range n.args {
+			a, err := p.compile(arg, vars)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = a
+		}
+		if len(p.scratch) < len(args) {
+			p.scratch = make([]complex128, len(args))
+		}
+		return p.emit(instr{kind: funcInstr, name: n.name, args: args}), nil
+	case *Piecewise:
+		// only the value of the first condition that holds is evaluated
+		p.regs = append(p.regs, 0)
+		dst := len(p.regs) - 1
+		ends := []int{}
+		for i := 0; i < len(n.args); i += 2 {
+			next := -1
+			value := n.args[i]
+			if i+1 < len(n.args) {
+				cond, err := p.compile(n.args[i], vars)
+				if err != nil {
+					return 0, err
+				}
+				p.code = append(p.code, instr{kind: jumpZeroInstr, a: cond})
+				next = len(p.code) - 1
+				value = n.args[i+1]
+			}
+
+			a, err := p.compile(value, vars)
+			if err != nil {
+				return 0, err
+			}
+			p.code = append(p.code, instr{kind: moveInstr, dst: dst, a: a}, instr{kind: jumpInstr})
+			ends = append(ends, len(p.code)-1)
+			if next != -1 {
+				p.code[next].target = len(p.code)
+			}
+		}
+		if len(n.args)%2 == 0 {
+			p.code = append(p.code, instr{kind: failInstr})
+		}
+		for _, end := range ends {
+			p.code[end].target = len(p.code)
+		}
+		return dst, nil
+	}
+	return 0, fmt.Errorf("cannot compile %T", in)
+}
+
+// emit appends an instruction that writes to a new register.
+func (p *Program) emit(in instr) int {
+	p.regs = append(p.regs, 0)
+	in.dst = len(p.regs) - 1
+	p.code = append(p.code, in)
+	return in.dst
+}
+
+// Set sets the value of a variable that was resolved at compile time.
+func (p *Program) Set(name string, value complex128) error {
+	i, ok := p.vars[name]
+	if !ok {
+		return fmt.Errorf("undefined variable '%s'", name)
+	}
+	p.regs[i] = value
+	return nil
+}
+
+// Call evaluates the program for the given arguments, which are bound in order to the parameters of the compiled function. It returns results bit-identical to Function.Call.
+func (p *Program) Call(args ...complex128) (complex128, error) {
+	if len(args) != len(p.params) {
+		return cmplx.NaN(), fmt.Errorf("function takes %d arguments, got %d", len(p.params), len(args))
+	}
+	for i, arg := range args {
+		p.regs[p.params[i]] = arg
+	}
+
+	var err error
+	regs := p.regs
+	for pc := 0; pc < len(p.code); pc++ {
+		in := &p.code[pc]
+		switch in.kind {
+		case exprInstr:
+			regs[in.dst], err = calcOp(in.op, regs[in.a], regs[in.b])
+		case unaryInstr:
+			regs[in.dst], err = calcUnary(in.op, regs[in.a])
+		case moveInstr:
+			regs[in.dst] = regs[in.a]
+		case jumpInstr:
+			pc = in.target - 1
+		case jumpZeroInstr:
+			if regs[in.a] == 0.0 {
+				pc = in.target - 1
+			}
+		case failInstr:
+			err = errNoCondition
+		case funcInstr:
+			ys := p.scratch[:len(in.args)]
+			for j, a := range in.args {
+				ys[j] = regs[a]
+			}
+			regs[in.dst], err = calcFunc(in.name, ys)
+		}
+		if err != nil {
+			return cmplx.NaN(), err
+		}
+	}
+	return regs[p.out], nil
+}
@@ -0,0 +1,104 @@
+package formulae
+
+import (
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []string{
+		"1+2*3",
+		"sin(x)^2+1/x+0.001x^3",
+		"-x^2+e^x",
+		"max(x, 2, pi)",
+		"atan2(x, 2)+log(2, x)",
+		"gamma(x)*erf(x/10)",
+		"sqrt(-x)",
+	}
+
+	xs := []complex128{-2.5, 0.5, 1, 3 + 1i}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			f, errs := Parse(test)
+			if len(errs) > 0 {
+				t.Fatal(f, errs)
+			}
+			p, err := f.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, x := range xs {
+				y, err := f.Calc(x)
+				y2, err2 := p.Call(x)
+				if (err == nil) != (err2 == nil) {
+					t.Fatal(err, "!=", err2)
+				} else if y != y2 && !(y != y && y2 != y2) {
+					t.Fatal(y2, "!=", y)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileErr(t *testing.T) {
+	f, _ := Parse("4y")
+	if _, err := f.Compile(); err == nil || err.Error() != "undefined variable 'y'" {
+		t.Fatal(err, "!=", "undefined variable 'y'")
+	}
+
+	f, _ = Parse("3/(a-x)")
+	f.Vars.Set("a", 5)
+	p, err := f.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Call(5); err == nil || err.Error() != "division by zero" {
+		t.Fatal(err, "!=", "division by zero")
+	}
+	if err := p.Set("a", 6); err != nil {
+		t.Fatal(err)
+	}
+	if y, err := p.Call(5); err != nil {
+		t.Fatal(err)
+	} else if y != 3 {
+		t.Fatal(y, "!=", 3)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	f, _ := Parse("2*x")
+	xs, ys, errs := f.Interval(0, 1, 4)
+	if len(xs) != 5 || len(ys) != 5 || len(errs) != 0 {
+		t.Fatal(xs, ys, errs)
+	} else if xs[3] != 3 || ys[3] != 6 {
+		t.Fatal(xs[3], ys[3], "!=", 3, 6)
+	}
+
+	// undefined variables fail to compile, but still give an error per sample
+	f, _ = Parse("x+y")
+	_, _, errs = f.Interval(0, 1, 4)
+	if len(errs) != 5 {
+		t.Fatal(len(errs), "!=", 5)
+	}
+}
+
+func TestCompileAllocs(t *testing.T) {
+	p, err := function.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		p.Call(5)
+	})
+	if allocs != 0 {
+		t.Fatal(allocs, "allocations per call")
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	p, _ := function.Compile()
+	for i := 0; i < b.N; i++ {
+		for i, x := range xs {
+			ys[i], _ = p.Call(x)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package formulae
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/cmplx"
+)
+
+// hashCons maps nodes to a single representative node for each class of structurally equal nodes, see structurallyEqual.
+type hashCons struct {
+	hashes  map[Node]uint64 // memoizes the hash of each node by identity
+	buckets map[uint64][]Node
+}
+
+func newHashCons() *hashCons {
+	return &hashCons{
+		hashes:  map[Node]uint64{},
+		buckets: map[uint64][]Node{},
+	}
+}
+
+// hash returns the structural hash of n, which is equal for structurally equal nodes.
+func (h *hashCons) hash(in Node) uint64 {
+	if v, ok := h.hashes[in]; ok {
+		return v
+	}
+
+	buf := [8]byte{}
+	w := fnv.New64a()
+	write := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		w.Write(buf[:])
+	}
+	write(uint64(nodeRank(in)))
+	switch n := in.(type) {
+	case *Number:
+		write(math.Float64bits(real(n.val) + 0.0)) // the same hash for 0 and -0
+		write(math.Float64bits(imag(n.val) + 0.0))
+	case *Variable:
+		w.Write([]byte(n.name))
+	case *Func:
+		write(uint64(n.name))
+		for _, arg := range n.args {
+			write(h.hash(arg))
+		}
+	case *Piecewise:
+		for _, arg := range n.args {
+			write(h.hash(arg))
+		}
+	case *UnaryExpr:
+		write(uint64(n.op))
+		write(h.hash(n.a))
+	case *Expr:
+		write(uint64(n.op))
+		l, r := h.hash(n.l), h.hash(n.r)
+		if (n.op == AddOp || n.op == MultiplyOp) && r < l {
+			// the hash does not depend on the order of the operands
+			l, r = r, l
+		}
+		write(l)
+		write(r)
+	}
+
+	v := w.Sum64()
+	h.hashes[in] = v
+	return v
+}
+
+// intern returns a node equivalent to n that was interned before and true, or n itself and false if there is none.
+func (h *hashCons) intern(in Node) (Node, bool) {
+	v := h.hash(in)
+	for _, n := range h.buckets[v] {
+		if n == in || structurallyEqual(n, in) {
+			return n, n != in
+		}
+	}
+	h.buckets[v] = append(h.buckets[v], in)
+	return in, false
+}
+
+// StructuralHash returns a hash of n that is equal for structurally equal nodes, that is for nodes that are equal up to swapping the operands of additions and multiplications.
+func StructuralHash(n Node) uint64 {
+	return newHashCons().hash(n)
+}
+
+// structurallyEqual returns whether a and b are equal up to swapping the operands of additions and multiplications. Unlike regrouping, swapping does not change the result in floating point.
+func structurallyEqual(a, b Node) bool {
+	switch n := a.(type) {
+	case *Expr:
+		m, ok := b.(*Expr)
+		if !ok || n.op != m.op {
+			return false
+		} else if structurallyEqual(n.l, m.l) && structurallyEqual(n.r, m.r) {
+			return true
+		}
+		return (n.op == AddOp || n.op == MultiplyOp) && structurallyEqual(n.l, m.r) && structurallyEqual(n.r, m.l)
+	case *UnaryExpr:
+		m, ok := b.(*UnaryExpr)
+		return ok && n.op == m.op && structurallyEqual(n.a, m.a)
+	case *Func:
+		m, ok := b.(*Func)
+		return ok && n.name == m.name && structurallyEqualArgs(n.args, m.args)
+	case *Piecewise:
+		m, ok := b.(*Piecewise)
+		return ok && structurallyEqualArgs(n.args, m.args)
+	}
+	return a.Equal(b)
+}
+
+func structurallyEqualArgs(as, bs []Node) bool {
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if !structurallyEqual(as[i], bs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////
+
+// dagNode is a node of a DAG whose operands are indices of earlier nodes.
+type dagNode struct {
+	node     Node
+	operands []int
+}
+
+// DAG is a formula in which structurally equal subexpressions are stored only once. Nodes are stored in evaluation order so that each unique subexpression is calculated once per evaluation, which is much faster for expressions with many repeated subexpressions, such as higher derivatives. A DAG is safe for concurrent use.
+type DAG struct {
+	nodes  []dagNode
+	shared int
+	Vars
+}
+
+// DAG returns f as a directed acyclic graph of unique subexpressions.
+func (f *Function) DAG() *DAG {
+	d := &DAG{
+		Vars: f.Vars,
+	}
+	d.add(f.root, newHashCons(), map[Node]int{})
+	return d
+}
+
+func (d *DAG) add(in Node, h *hashCons, index map[Node]int) int {
+	if i, ok := index[in]; ok {
+		d.shared++
+		return i
+	} else if n, ok := h.intern(in); ok {
+		d.shared++
+		index[in] = index[n]
+		return index[n]
+	}
+
+	cs := children(in)
+	operands := make([]int, len(cs))
+	for i, c := range cs {
+		operands[i] = d.add(c, h, index)
+	}
+	d.nodes = append(d.nodes, dagNode{in, operands})
+	index[in] = len(d.nodes) - 1
+	return len(d.nodes) - 1
+}
+
+// Len returns the number of unique subexpressions.
+func (d *DAG) Len() int {
+	return len(d.nodes)
+}
+
+// Shared returns the number of subexpressions that were replaced by a structurally equal subexpression that occurred before.
+func (d *DAG) Shared() int {
+	return d.shared
+}
+
+// Calc evaluates the DAG for the given value of x.
+func (d *DAG) Calc(x complex128) (complex128, error) {
+	vars := scratchVars(d.Vars)
+	defer releaseVars(vars)
+	vars["x"] = x
+	return d.calc(vars)
+}
+
+// Eval evaluates the DAG where the variables in vars take precedence over the variables of the DAG.
+func (d *DAG) Eval(vars Vars) (complex128, error) {
+	all := scratchVars(d.Vars)
+	defer releaseVars(all)
+	for name, val := range vars {
+		all[name] = val
+	}
+	return d.calc(all)
+}
+
+// dagCalc holds the values of the nodes of a DAG during one evaluation.
+type dagCalc struct {
+	*DAG
+	vars Vars
+	ys   []complex128
+	errs []error
+	done []bool
+}
+
+// calc evaluates the root node. Each node is evaluated at most once, and the branches of piecewise functions that are not selected are not evaluated at all.
+func (d *DAG) calc(vars Vars) (complex128, error) {
+	c := &dagCalc{
+		DAG:  d,
+		vars: vars,
+		ys:   make([]complex128, len(d.nodes)),
+		errs: make([]error, len(d.nodes)),
+		done: make([]bool, len(d.nodes)),
+	}
+	return c.calc(len(d.nodes) - 1)
+}
+
+func (c *dagCalc) calc(i int) (complex128, error) {
+	if c.done[i] {
+		return c.ys[i], c.errs[i]
+	}
+	c.ys[i], c.errs[i] = c.calcNode(i)
+	c.done[i] = true
+	return c.ys[i], c.errs[i]
+}
+
+func (c *dagCalc) calcNode(i int) (complex128, error) {
+	dn := c.nodes[i]
+	if _, ok := dn.node.(*Piecewise); ok {
+		for j := 0; j+1 < len(dn.operands); j += 2 {
+			cond, err := c.calc(dn.operands[j])
+			if err != nil {
+				return cmplx.NaN(), err
+			} else if cond != 0.0 {
+				return c.calc(dn.operands[j+1])
+			}
+		}
+		if len(dn.operands)%2 == 1 {
+			return c.calc(dn.operands[len(dn.operands)-1])
+		}
+		return cmplx.NaN(), errNoCondition
+	}
+
+	for _, k := range dn.operands {
+		if _, err := c.calc(k); err != nil {
+			return cmplx.NaN(), err
+		}
+	}
+
+	switch n := dn.node.(type) {
+	case *Number:
+		return n.val, nil
+	case *Variable:
+		if y, ok := c.vars[n.name]; ok {
+			return y, nil
+		}
+		return cmplx.NaN(), fmt.Errorf("undefined variable '%s'", n.name)
+	case *UnaryExpr:
+		return calcUnary(n.op, c.ys[dn.operands[0]])
+	case *Expr:
+		return calcOp(n.op, c.ys[dn.operands[0]], c.ys[dn.operands[1]])
+	case *Func:
+		args := make([]complex128, len(dn.operands))
+		for j, k := range dn.operands {
+			args[j] = c.ys[k]
+		}
+		return calcFunc(n.name, args)
+	}
+	return dn.node.Calc(c.vars)
+}
@@ -0,0 +1,131 @@
+package formulae
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestStructuralHash(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"x+y", "y+x", true},
+		{"2*(x*y)", "(y*x)*2", true},
+		{"(x+y)+z", "x+(y+z)", false},
+		{"sin(x)^2", "sin(x)^2", true},
+		{"x-y", "y-x", false},
+		{"x/y", "y/x", false},
+		{"sin(x)", "cos(x)", false},
+		{"x+y", "x*y", false},
+		{"1", "x", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+"="+test.b, func(t *testing.T) {
+			f, errs := Parse(test.a)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			g, errs := Parse(test.b)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if equal := StructuralHash(f.root) == StructuralHash(g.root); equal != test.equal {
+				t.Fatal(equal, "!=", test.equal)
+			}
+		})
+	}
+}
+
+func TestDAG(t *testing.T) {
+	tests := []struct {
+		in     string
+		len    int
+		shared int
+	}{
+		{"x", 1, 0},
+		{"sin(x)+sin(x)", 3, 1},
+		{"(x+y)*(y+x)", 4, 1},
+		{"sin(x^2)*cos(x^2)+x^2", 7, 2},
+		{"x-y+y-x", 5, 2},
+		{"(a+b)+c+(a+(b+c))", 8, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			d := f.DAG()
+			if d.Len() != test.len {
+				t.Fatal(d.Len(), "!=", test.len)
+			} else if d.Shared() != test.shared {
+				t.Fatal(d.Shared(), "!=", test.shared)
+			}
+		})
+	}
+}
+
+func TestDAGCalc(t *testing.T) {
+	tests := []string{
+		"sin(cos(x))^2+1/x-1",
+		"x^x",
+		"(x+y)*(y+x)/sqrt(x+y)",
+		"atan2(x, y)+atan2(y, x)",
+		"-x*-x",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			f, errs := Parse(test)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Vars = Vars{"y": 2.0}
+			for i := 0; i < 3; i++ {
+				d := f.DAG()
+				for _, x := range []complex128{0.5, 1.5 + 0.5i} {
+					want, err := f.Calc(x)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := d.Calc(x)
+					if err != nil {
+						t.Fatal(err)
+					} else if 1e-12 < cmplx.Abs(got-want) {
+						t.Fatal(got, "!=", want)
+					}
+				}
+				f = f.Derivative()
+				f.Vars = Vars{"y": 2.0}
+			}
+		})
+	}
+}
+
+func TestDAGErr(t *testing.T) {
+	f, errs := Parse("1/x+y")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if _, err := f.DAG().Calc(0.0); err == nil || err.Error() != "division by zero" {
+		t.Fatal(err, "!=", "division by zero")
+	} else if _, err := f.DAG().Calc(1.0); err == nil || err.Error() != "undefined variable 'y'" {
+		t.Fatal(err, "!=", "undefined variable 'y'")
+	}
+}
+
+func TestDAGPiecewise(t *testing.T) {
+	f, errs := Parse("x < 0 ? y : 1/x")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	d := f.DAG()
+	if y, err := d.Calc(2.0); err != nil || y != 0.5 {
+		t.Fatal(y, err, "!=", 0.5)
+	} else if _, err := d.Calc(-1.0); err == nil || err.Error() != "undefined variable 'y'" {
+		t.Fatal(err, "!=", "undefined variable 'y'")
+	}
+}
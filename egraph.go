@@ -0,0 +1,487 @@
+package formulae
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strings"
+)
+
+const (
+	maxSaturationIterations = 12   // limits the number of rounds of rewriting
+	maxEGraphNodes          = 5000 // limits the size of the e-graph
+	maxRuleMatches          = 1000 // limits the number of matches of a rule per iteration
+)
+
+// CostFunc returns the cost of node n given the costs of its operands. The operands of n are the cheapest equivalent expressions found so far.
+type CostFunc func(n Node, operands []float64) float64
+
+// NodeCost counts the number of nodes.
+func NodeCost(n Node, operands []float64) float64 {
+	cost := 1.0
+	for _, c := range operands {
+		cost += c
+	}
+	return cost
+}
+
+// EvalCost estimates the cost of evaluation, where divisions, powers and functions are more expensive than additions and multiplications.
+func EvalCost(in Node, operands []float64) float64 {
+	cost := 0.0
+	switch n := in.(type) {
+	case *Expr:
+		switch n.op {
+		case AddOp, SubtractOp:
+			cost = 1.0
+		case MultiplyOp:
+			cost = 2.0
+		case DivideOp, ModOp:
+			cost = 4.0
+		case PowerOp:
+			cost = 20.0
+			if k, ok := n.r.(*Number); ok && (k.val == 2.0 || k.val == 3.0) {
+				cost = 4.0 // computed by multiplication
+			}
+		}
+	case *UnaryExpr:
+		cost = 1.0
+		if n.op == FactorialOp {
+			cost = 20.0 // computed by the gamma function
+		}
+	case *Func:
+		cost = 20.0
+	}
+	for _, c := range operands {
+		cost += c
+	}
+	return cost
+}
+
+// StabilityCost penalizes operations that may lose precision, such as subtraction that cancels digits and division and powers that amplify relative errors, and otherwise counts the number of nodes.
+func StabilityCost(in Node, operands []float64) float64 {
+	cost := 1.0
+	if n, ok := in.(*Expr); ok {
+		switch n.op {
+		case SubtractOp:
+			cost = 8.0
+		case DivideOp, PowerOp:
+			cost = 3.0
+		}
+	}
+	for _, c := range operands {
+		cost += c
+	}
+	return cost
+}
+
+// egraphRules are the algebraic rules of Optimize, together with commutativity, associativity and factoring.
+var egraphRules = mustParseRules(
+	// commutativity and associativity
+	"?a+?b -> ?b+?a",
+	"?a*?b -> ?b*?a",
+	"(?a+?b)+?c -> ?a+(?b+?c)",
+	"?a+(?b+?c) -> (?a+?b)+?c",
+	"(?a*?b)*?c -> ?a*(?b*?c)",
+	"?a*(?b*?c) -> (?a*?b)*?c",
+	"(?a+?b)-?c -> ?a+(?b-?c)",
+
+	// identity elements
+	"?a+0 -> ?a",
+	"?a-0 -> ?a",
+	"0-?a -> -?a",
+	"?a*0 -> 0",
+	"?a*1 -> ?a",
+	"?a/1 -> ?a",
+	"?a^0 -> 1",
+	"?a^1 -> ?a",
+	"1^?a -> 1",
+
+	// negation and subtraction
+	"--?a -> ?a",
+	"?a+-?b -> ?a-?b",
+	"?a--?b -> ?a+?b",
+	"?a-?b -> ?a+-?b",
+	"-?a*?b -> -(?a*?b)",
+	"-?a/?b -> -(?a/?b)",
+	"?a/-?b -> -(?a/?b)",
+	"?a^-?b -> 1/?a^?b",
+	"?a+?a -> 2*?a",
+	"?a*?a -> ?a^2",
+	"?a*?b+?a*?c -> ?a*(?b+?c)",
+	"?a^?b*?a -> ?a^(?b+1)",
+	"?a^?b*?a^?c -> ?a^(?b+?c)",
+
+	// functions
+	"e^log(?a) -> ?a",
+	"10^log10(?a) -> ?a",
+	"2^log2(?a) -> ?a",
+	"log(e) -> 1",
+	"sin(-?a) -> -sin(?a)",
+	"tan(-?a) -> -tan(?a)",
+	"erf(-?a) -> -erf(?a)",
+	"cbrt(-?a) -> -cbrt(?a)",
+	"cos(-?a) -> cos(?a)",
+)
+
+func mustParseRules(ins ...string) []*Rule {
+	rules := make([]*Rule, len(ins))
+	for i, in := range ins {
+		rule, errs := ParseRule(in)
+		if len(errs) != 0 {
+			panic(fmt.Sprintf("bad rule '%s': %v", in, errs[0]))
+		}
+		rules[i] = rule
+	}
+	return rules
+}
+
+// enode is a node of an e-graph whose operands are e-classes. The node holds the operator, function name, number or variable, while its own operands are ignored.
+type enode struct {
+	node     Node
+	children []int
+}
+
+// egraph is a set of e-classes of equivalent expressions, where each e-class is a set of e-nodes.
+type egraph struct {
+	parent  []int
+	classes map[int][]enode
+	memo    map[string]int
+	size    int
+}
+
+func newEGraph() *egraph {
+	return &egraph{
+		classes: map[int][]enode{},
+		memo:    map[string]int{},
+	}
+}
+
+func (g *egraph) find(id int) int {
+	for g.parent[id] != id {
+		g.parent[id] = g.parent[g.parent[id]]
+		id = g.parent[id]
+	}
+	return id
+}
+
+// key returns the canonical key of an e-node, which is equal for e-nodes with the same operator and operands.
+func (g *egraph) key(n enode) string {
+	sb := strings.Builder{}
+	switch m := n.node.(type) {
+	case *Number:
+		fmt.Fprintf(&sb, "n%v", m.val)
+	case *Variable:
+		fmt.Fprintf(&sb, "v%s", m.name)
+	case *Expr:
+		fmt.Fprintf(&sb, "e%d", m.op)
+	case *UnaryExpr:
+		fmt.Fprintf(&sb, "u%d", m.op)
+	case *Func:
+		fmt.Fprintf(&sb, "f%d", m.name)
+	case *Piecewise:
+		sb.WriteString("p")
+	}
+	for _, c := range n.children {
+		fmt.Fprintf(&sb, ",%d", g.find(c))
+	}
+	return sb.String()
+}
+
+// add adds an e-node and returns its e-class.
+func (g *egraph) add(n Node, cs []int) int {
+	e := enode{n, make([]int, len(cs))}
+	for i, c := range cs {
+		e.children[i] = g.find(c)
+	}
+	key := g.key(e)
+	if id, ok := g.memo[key]; ok {
+		return g.find(id)
+	}
+	id := len(g.parent)
+	g.parent = append(g.parent, id)
+	g.classes[id] = []enode{e}
+	g.memo[key] = id
+	g.size++
+	return id
+}
+
+// addTree adds an expression and returns its e-class.
+func (g *egraph) addTree(n Node) int {
+	ns := children(n)
+	cs := make([]int, len(ns))
+	for i, c := range ns {
+		cs[i] = g.addTree(c)
+	}
+	return g.add(n, cs)
+}
+
+// union merges two e-classes and returns whether they were different.
+func (g *egraph) union(a, b int) bool {
+	a, b = g.find(a), g.find(b)
+	if a == b {
+		return false
+	} else if b < a {
+		a, b = b, a
+	}
+	g.parent[b] = a
+	g.classes[a] = append(g.classes[a], g.classes[b]...)
+	delete(g.classes, b)
+	return true
+}
+
+// ids returns the e-classes in a deterministic order.
+func (g *egraph) ids() []int {
+	ids := make([]int, 0, len(g.classes))
+	for id := range g.classes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// rebuild restores congruence, merging e-classes that have e-nodes with the same operator and equivalent operands, and removes duplicate e-nodes.
+func (g *egraph) rebuild() {
+	for {
+		changed := false
+		g.memo = map[string]int{}
+		for _, id := range g.ids() {
+			for _, n := range g.classes[g.find(id)] {
+				key := g.key(n)
+				if other, ok := g.memo[key]; ok {
+					changed = g.union(other, id) || changed
+				} else {
+					g.memo[key] = id
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	g.size = 0
+	for _, id := range g.ids() {
+		seen := map[string]bool{}
+		ns := g.classes[id][:0]
+		for _, n := range g.classes[id] {
+			for i, c := range n.children {
+				n.children[i] = g.find(c)
+			}
+			if key := g.key(n); !seen[key] {
+				seen[key] = true
+				ns = append(ns, n)
+			}
+		}
+		g.classes[id] = ns
+		g.size += len(ns)
+	}
+}
+
+// sameOperator returns whether the pattern node and the e-node have the same operator and number of operands.
+func sameOperator(pattern Node, n enode) bool {
+	switch p := pattern.(type) {
+	case *Expr:
+		m, ok := n.node.(*Expr)
+		return ok && p.op == m.op
+	case *UnaryExpr:
+		m, ok := n.node.(*UnaryExpr)
+		return ok && p.op == m.op
+	case *Func:
+		m, ok := n.node.(*Func)
+		return ok && p.name == m.name && len(p.args) == len(n.children)
+	case *Piecewise:
+		_, ok := n.node.(*Piecewise)
+		return ok && len(p.args) == len(n.children)
+	}
+	return pattern.Equal(n.node)
+}
+
+// ematch returns all bindings of pattern variables to e-classes for which the pattern matches the e-class.
+func (g *egraph) ematch(pattern Node, id int, b map[string]int) []map[string]int {
+	id = g.find(id)
+	if name, ok := isPatternVariable(pattern); ok {
+		if bound, ok := b[name]; ok {
+			if g.find(bound) == id {
+				return []map[string]int{b}
+			}
+			return nil
+		}
+		c := make(map[string]int, len(b)+1)
+		for k, v := range b {
+			c[k] = v
+		}
+		c[name] = id
+		return []map[string]int{c}
+	}
+
+	ns := g.classes[id]
+	if num, ok := g.constant(id); ok {
+		ns = []enode{{node: num}} // constants only match as numbers, which avoids cycles such as 0 = 0*0
+	}
+
+	var matches []map[string]int
+	ps := children(pattern)
+	for _, n := range ns {
+		if !sameOperator(pattern, n) {
+			continue
+		}
+		bs := []map[string]int{b}
+		for i, p := range ps {
+			var next []map[string]int
+			for _, b := range bs {
+				next = append(next, g.ematch(p, n.children[i], b)...)
+			}
+			bs = next
+		}
+		matches = append(matches, bs...)
+	}
+	return matches
+}
+
+// addPattern adds the pattern with its variables replaced by their e-classes.
+func (g *egraph) addPattern(pattern Node, b map[string]int) int {
+	if name, ok := isPatternVariable(pattern); ok {
+		return g.find(b[name])
+	} else if v, ok := pattern.(*Variable); ok {
+		pattern = &Variable{name: v.name, pos: -1} // the position refers to the rule, not to the formula
+	}
+	ps := children(pattern)
+	cs := make([]int, len(ps))
+	for i, p := range ps {
+		cs[i] = g.addPattern(p, b)
+	}
+	return g.add(pattern, cs)
+}
+
+// constant returns the number in an e-class, if any.
+func (g *egraph) constant(id int) (*Number, bool) {
+	for _, n := range g.classes[g.find(id)] {
+		if nNumber, ok := n.node.(*Number); ok {
+			return nNumber, true
+		}
+	}
+	return nil, false
+}
+
+// foldConstants adds the numbers that Optimize folds for e-nodes whose operands are numbers.
+func (g *egraph) foldConstants() bool {
+	changed := false
+	for _, id := range g.ids() {
+		for _, n := range g.classes[id] {
+			if len(n.children) == 0 {
+				continue
+			}
+			cs := make([]Node, len(n.children))
+			for i, c := range n.children {
+				num, ok := g.constant(c)
+				if !ok {
+					cs = nil
+					break
+				}
+				cs[i] = num
+			}
+			if cs == nil {
+				continue
+			}
+			if num, ok := Optimize(withChildren(n.node, cs)).(*Number); ok && !cmplx.IsNaN(num.val) && !cmplx.IsInf(num.val) {
+				changed = g.union(id, g.add(num, nil)) || changed
+			}
+		}
+	}
+	return changed
+}
+
+// saturate applies the rules until no new equivalences are found or a limit is reached.
+func (g *egraph) saturate(rules []*Rule) {
+	type match struct {
+		rule *Rule
+		id   int
+		b    map[string]int
+	}
+	for i := 0; i < maxSaturationIterations && g.size < maxEGraphNodes; i++ {
+		var matches []match
+		for _, rule := range rules {
+			var ms []match
+			for _, id := range g.ids() {
+				for _, b := range g.ematch(rule.lhs, id, map[string]int{}) {
+					ms = append(ms, match{rule, id, b})
+				}
+				if maxRuleMatches < len(ms) {
+					break
+				}
+			}
+			if len(ms) <= maxRuleMatches {
+				matches = append(matches, ms...) // skip rules that match too often, such as associativity in large e-graphs
+			}
+		}
+
+		changed := false
+		for _, m := range matches {
+			changed = g.union(m.id, g.addPattern(m.rule.rhs, m.b)) || changed
+			if maxEGraphNodes <= len(g.parent) {
+				break
+			}
+		}
+		g.rebuild()
+		if g.foldConstants() {
+			changed = true
+			g.rebuild()
+		}
+		if !changed {
+			break
+		}
+	}
+}
+
+// extract returns the cheapest expression of an e-class.
+func (g *egraph) extract(root int, cost CostFunc) Node {
+	best := map[int]Node{}
+	costs := map[int]float64{}
+	ids := g.ids()
+
+	// each pass extracts at least the classes one level deeper, so the number of classes bounds the passes even if costs keep decreasing around a cycle
+	for pass, changed := 0, true; changed && pass < len(ids); pass++ {
+		changed = false
+		for _, id := range ids {
+			for _, n := range g.classes[id] {
+				cs := make([]Node, len(n.children))
+				ccs := make([]float64, len(n.children))
+				ok := true
+				for i, c := range n.children {
+					c = g.find(c)
+					if cs[i], ok = best[c]; !ok {
+						break
+					}
+					ccs[i] = costs[c]
+				}
+				if !ok {
+					continue
+				}
+
+				m := withChildren(n.node, cs)
+				if c := cost(m, ccs); !math.IsNaN(c) {
+					if prev, ok := costs[id]; !ok || c < prev {
+						best[id] = m
+						costs[id] = c
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return best[g.find(root)]
+}
+
+// OptimizeEGraph returns the cheapest expression equivalent to n under the cost function. It inserts n into an e-graph, saturates it with the rules of Optimize together with commutativity, associativity and factoring, and extracts the cheapest equivalent expression. Unlike Optimize it does not modify n. Saturation stops after a fixed number of iterations or when the e-graph grows too large.
+func OptimizeEGraph(n Node, cost CostFunc) Node {
+	g := newEGraph()
+	root := g.addTree(n)
+	g.saturate(egraphRules)
+	return g.extract(root, cost)
+}
+
+// OptimizeEGraph replaces f by its cheapest equivalent expression under the cost function, such as NodeCost, EvalCost or StabilityCost.
+func (f *Function) OptimizeEGraph(cost CostFunc) {
+	f.root = OptimizeEGraph(f.root, cost)
+}
@@ -0,0 +1,69 @@
+package formulae
+
+import "testing"
+
+func TestOptimizeEGraph(t *testing.T) {
+	tests := []struct {
+		in   string
+		cost CostFunc
+		out  string
+	}{
+		{"x*0+y", NodeCost, "y"},
+		{"(x+1)-(x+1)", NodeCost, "x+1-(x+1)"}, // not zero for infinite x
+		{"0/x", NodeCost, "0/x"},               // not zero for x=0
+		{"(2*x)*3", NodeCost, "x*6"},
+		{"x*2*y*3", NodeCost, "x*y*6"},
+		{"1+2*x+3*x", NodeCost, "1+x*5"},
+		{"a*b+a*c", NodeCost, "a*(b+c)"},
+		{"e^ln(x)", NodeCost, "x"},
+		{"x*x*x*x", NodeCost, "x^4"},
+		{"x^2*x^3*y", NodeCost, "x^5*y"},
+		{"x^2*x^3*y", EvalCost, "x^2*x^3*y"},
+		{"x^-1", EvalCost, "1/x"},
+		{"x^-1", StabilityCost, "x^-1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.OptimizeEGraph(test.cost)
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestOptimizeEGraphDecreasingCost(t *testing.T) {
+	f, errs := Parse("--x*1")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	// costs that decrease with the size of the expression never settle around the cycle x = --x
+	f.OptimizeEGraph(func(n Node, operands []float64) float64 {
+		cost := -1.0
+		for _, c := range operands {
+			cost += c
+		}
+		return cost
+	})
+	if y, err := f.Calc(2.0); err != nil || y != 2.0 {
+		t.Fatal(y, err, "!=", 2.0)
+	}
+}
+
+func TestOptimizeEGraphNonDestructive(t *testing.T) {
+	f, errs := Parse("x*0+y")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if n := OptimizeEGraph(f.root, NodeCost); n.String() != "y" {
+		t.Fatal(n, "!=", "y")
+	} else if f.String() != "x*0+y" {
+		t.Fatal(f, "!=", "x*0+y")
+	}
+}
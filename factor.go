@@ -0,0 +1,418 @@
+package formulae
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// maxRootCandidate limits the constant and leading coefficients whose divisors are tried as rational roots.
+const maxRootCandidate = 1e12
+
+// ratPoly is a univariate polynomial with rational coefficients in ascending order of power, without trailing zeros.
+type ratPoly []*big.Rat
+
+func (p ratPoly) trim() ratPoly {
+	for 0 < len(p) && p[len(p)-1].Sign() == 0 {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// degree returns the degree of p, which is -1 for the zero polynomial.
+func (p ratPoly) degree() int {
+	return len(p) - 1
+}
+
+func (p ratPoly) lead() *big.Rat {
+	return p[len(p)-1]
+}
+
+func (p ratPoly) add(q ratPoly, sign int) ratPoly {
+	n := len(p)
+	if n < len(q) {
+		n = len(q)
+	}
+	r := make(ratPoly, n)
+	for i := range r {
+		r[i] = new(big.Rat)
+		if i < len(p) {
+			r[i].Set(p[i])
+		}
+		if i < len(q) {
+			if sign < 0 {
+				r[i].Sub(r[i], q[i])
+			} else {
+				r[i].Add(r[i], q[i])
+			}
+		}
+	}
+	return r.trim()
+}
+
+func (p ratPoly) mul(q ratPoly) ratPoly {
+	if len(p) == 0 || len(q) == 0 {
+		return ratPoly{}
+	}
+	r := make(ratPoly, len(p)+len(q)-1)
+	for i := range r {
+		r[i] = new(big.Rat)
+	}
+	t := new(big.Rat)
+	for i, a := range p {
+		for j, b := range q {
+			r[i+j].Add(r[i+j], t.Mul(a, b))
+		}
+	}
+	return r.trim()
+}
+
+func (p ratPoly) scale(c *big.Rat) ratPoly {
+	r := make(ratPoly, len(p))
+	for i, a := range p {
+		r[i] = new(big.Rat).Mul(a, c)
+	}
+	return r.trim()
+}
+
+// divmod returns the quotient and remainder of p divided by the non-zero polynomial q.
+func (p ratPoly) divmod(q ratPoly) (ratPoly, ratPoly) {
+	rem := p.scale(big.NewRat(1, 1))
+	if len(rem) < len(q) {
+		return ratPoly{}, rem
+	}
+	quo := make(ratPoly, len(p)-len(q)+1)
+	for i := range quo {
+		quo[i] = new(big.Rat)
+	}
+	t := new(big.Rat)
+	for len(q) <= len(rem) {
+		k := len(rem) - len(q)
+		c := new(big.Rat).Quo(rem.lead(), q.lead())
+		quo[k] = c
+		for i, b := range q {
+			rem[i+k].Sub(rem[i+k], t.Mul(c, b))
+		}
+		rem = rem[:len(rem)-1].trim()
+	}
+	return quo.trim(), rem
+}
+
+func (p ratPoly) monic() ratPoly {
+	if len(p) == 0 {
+		return p
+	}
+	return p.scale(new(big.Rat).Inv(p.lead()))
+}
+
+func (p ratPoly) derivative() ratPoly {
+	if len(p) < 2 {
+		return ratPoly{}
+	}
+	r := make(ratPoly, len(p)-1)
+	for i := range r {
+		r[i] = new(big.Rat).Mul(p[i+1], big.NewRat(int64(i+1), 1))
+	}
+	return r.trim()
+}
+
+func (p ratPoly) eval(x *big.Rat) *big.Rat {
+	y := new(big.Rat)
+	for i := len(p) - 1; 0 <= i; i-- {
+		y.Mul(y, x)
+		y.Add(y, p[i])
+	}
+	return y
+}
+
+// primitive returns the content c and primitive part q of p such that p = c*q, where q has coprime integer coefficients and a positive leading coefficient.
+func (p ratPoly) primitive() (*big.Rat, ratPoly) {
+	if len(p) == 0 {
+		return new(big.Rat), p
+	}
+	num := new(big.Int)
+	den := big.NewInt(1)
+	for _, a := range p {
+		num.GCD(nil, nil, num, a.Num())
+		den.Div(new(big.Int).Mul(den, a.Denom()), new(big.Int).GCD(nil, nil, den, a.Denom()))
+	}
+	c := new(big.Rat).SetFrac(num, den)
+	if p.lead().Sign() < 0 {
+		c.Neg(c)
+	}
+	return c, p.scale(new(big.Rat).Inv(c))
+}
+
+// gcdRatPoly returns the monic greatest common divisor of p and q.
+func gcdRatPoly(p, q ratPoly) ratPoly {
+	for len(q) != 0 {
+		_, r := p.divmod(q)
+		p, q = q, r
+	}
+	return p.monic()
+}
+
+// squareFree returns the square-free decomposition of the monic polynomial p using Yun's algorithm, where the i-th polynomial has roots of multiplicity i+1.
+func (p ratPoly) squareFree() []ratPoly {
+	dp := p.derivative()
+	b := gcdRatPoly(p, dp)
+	c, _ := p.divmod(b)
+	d, _ := dp.divmod(b)
+	d = d.add(c.derivative(), -1)
+
+	fs := []ratPoly{}
+	for 0 < c.degree() {
+		a := gcdRatPoly(c, d)
+		c, _ = c.divmod(a)
+		d, _ = d.divmod(a)
+		d = d.add(c.derivative(), -1)
+		fs = append(fs, a)
+	}
+	return fs
+}
+
+// rationalRoots splits p into linear factors of its rational roots and a remaining factor without rational roots, using the rational root theorem.
+func (p ratPoly) rationalRoots() ([]*big.Rat, ratPoly) {
+	roots := []*big.Rat{}
+	for 0 < p.degree() && p[0].Sign() == 0 {
+		roots = append(roots, new(big.Rat))
+		p = p[1:]
+	}
+	if p.degree() < 1 {
+		return roots, p
+	}
+
+	_, q := p.primitive()
+	a0, an := q[0].Num(), q.lead().Num()
+	if !a0.IsInt64() || !an.IsInt64() || maxRootCandidate < math.Abs(float64(a0.Int64())) || maxRootCandidate < math.Abs(float64(an.Int64())) {
+		return roots, p
+	}
+	for _, num := range divisors(a0.Int64()) {
+		for _, den := range divisors(an.Int64()) {
+			for _, sign := range []int64{1, -1} {
+				r := big.NewRat(sign*num, den)
+				for 0 < p.degree() && p.eval(r).Sign() == 0 {
+					roots = append(roots, r)
+					p, _ = p.divmod(ratPoly{new(big.Rat).Neg(r), big.NewRat(1, 1)})
+				}
+			}
+		}
+	}
+	return roots, p
+}
+
+// divisors returns the positive divisors of n.
+func divisors(n int64) []int64 {
+	if n < 0 {
+		n = -n
+	}
+	ds := []int64{}
+	large := []int64{}
+	for i := int64(1); i*i <= n; i++ {
+		if n%i == 0 {
+			ds = append(ds, i)
+			if i*i != n {
+				large = append(large, n/i)
+			}
+		}
+	}
+	for i := len(large) - 1; 0 <= i; i-- {
+		ds = append(ds, large[i])
+	}
+	return ds
+}
+
+// toRatPoly returns p as a univariate polynomial with rational coefficients and the name of its variable, which is empty for a constant. It returns false if p has more than one variable, non-polynomial atoms, negative powers, or non-real coefficients.
+func (p polynomial) toRatPoly() (ratPoly, string, bool) {
+	name := ""
+	r := ratPoly{}
+	for _, m := range p {
+		if imag(m.coef) != 0.0 || math.IsNaN(real(m.coef)) || math.IsInf(real(m.coef), 0) {
+			return nil, "", false
+		}
+		k := 0
+		if 1 < len(m.factors) {
+			return nil, "", false
+		} else if len(m.factors) == 1 {
+			v, ok := m.factors[0].atom.(*Variable)
+			if !ok || name != "" && v.name != name || m.factors[0].exp < 0 {
+				return nil, "", false
+			}
+			name = v.name
+			k = m.factors[0].exp
+		}
+
+		// use the shortest decimal representation so that 0.1 becomes 1/10
+		c, ok := new(big.Rat).SetString(strconv.FormatFloat(real(m.coef), 'g', -1, 64))
+		if !ok {
+			return nil, "", false
+		}
+		for len(r) <= k {
+			r = append(r, new(big.Rat))
+		}
+		r[k].Add(r[k], c)
+	}
+	return r.trim(), name, true
+}
+
+// polynomial returns p as a polynomial in the variable name.
+func (p ratPoly) polynomial(name string) polynomial {
+	r := polynomial{}
+	x := &Variable{name: name, pos: -1}
+	for k, a := range p {
+		c, _ := a.Float64()
+		m := monomial{coef: complex(c, 0.0)}
+		if 0 < k {
+			m.factors = []factor{{name, x, k}}
+		}
+		r = append(r, m)
+	}
+	return r.normalize()
+}
+
+// factorNode returns n factored over the rationals if it is a univariate polynomial, otherwise it factors its subexpressions.
+func factorNode(in Node) Node {
+	if p, name, ok := toPolynomial(in, true).toRatPoly(); ok && 0 < p.degree() {
+		return factorRatPoly(p, name)
+	}
+
+	switch n := in.(type) {
+	case *Expr:
+		return &Expr{op: n.op, l: factorNode(n.l), r: factorNode(n.r)}
+	case *UnaryExpr:
+		return &UnaryExpr{op: n.op, a: factorNode(n.a)}
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = factorNode(arg)
+		}
+		return &Func{name: n.name, args: args}
+	case *Piecewise:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = factorNode(arg)
+		}
+		return &Piecewise{args: args}
+	}
+	return in
+}
+
+type ratFactor struct {
+	p    ratPoly
+	node Node
+	mult int
+}
+
+// factorRatPoly returns the product of the content, the linear factors of rational roots, and the remaining square-free factors of p.
+func factorRatPoly(p ratPoly, name string) Node {
+	c, q := p.primitive()
+	factors := []ratFactor{}
+	for i, s := range q.monic().squareFree() {
+		roots, rest := s.rationalRoots()
+		for _, r := range roots {
+			_, lin := ratPoly{new(big.Rat).Neg(r), big.NewRat(1, 1)}.primitive()
+			factors = append(factors, ratFactor{lin, nil, i + 1})
+		}
+		if 0 < rest.degree() {
+			factors = append(factors, ratFactor{rest, nil, i + 1})
+		}
+	}
+
+	// multiply the content by the ratio of the primitive factors and q
+	prod := ratPoly{big.NewRat(1, 1)}
+	for i, f := range factors {
+		_, pf := f.p.primitive()
+		factors[i].p = pf
+		for j := 0; j < f.mult; j++ {
+			prod = prod.mul(pf)
+		}
+		factors[i].node = pf.polynomial(name).node()
+	}
+	c.Mul(c, new(big.Rat).Quo(q.lead(), prod.lead()))
+	sort.SliceStable(factors, func(i, j int) bool {
+		if factors[i].p.degree() != factors[j].p.degree() {
+			return factors[i].p.degree() < factors[j].p.degree()
+		}
+		si, sj := factors[i].node.String(), factors[j].node.String()
+		if len(si) != len(sj) {
+			return len(si) < len(sj)
+		}
+		return si < sj
+	})
+
+	var root Node
+	for _, f := range factors {
+		n := f.node
+		if 1 < f.mult {
+			n = &Expr{op: PowerOp, l: n, r: &Number{val: complex(float64(f.mult), 0.0)}}
+		}
+		if root == nil {
+			root = n
+		} else {
+			root = &Expr{op: MultiplyOp, l: root, r: n}
+		}
+	}
+
+	cf, _ := c.Float64()
+	if cf == -1.0 {
+		return negateNode(root)
+	} else if cf != 1.0 {
+		return &Expr{op: MultiplyOp, l: &Number{val: complex(cf, 0.0)}, r: root}
+	}
+	return root
+}
+
+// cancelNode cancels the greatest common divisor of the numerator and denominator of divisions of univariate polynomials.
+func cancelNode(in Node) Node {
+	switch n := in.(type) {
+	case *Expr:
+		l, r := cancelNode(n.l), cancelNode(n.r)
+		if n.op == DivideOp {
+			num, numName, okNum := toPolynomial(l, true).toRatPoly()
+			den, denName, okDen := toPolynomial(r, true).toRatPoly()
+			if okNum && okDen && len(den) != 0 && (numName == denName || numName == "" || denName == "") {
+				name := numName
+				if name == "" {
+					name = denName
+				}
+
+				g := gcdRatPoly(num, den)
+				if 0 < g.degree() || len(num) == 0 {
+					num, _ = num.divmod(g)
+					den, _ = den.divmod(g)
+					if den.degree() == 0 {
+						return num.scale(new(big.Rat).Inv(den[0])).polynomial(name).node()
+					}
+					return &Expr{op: DivideOp, l: num.polynomial(name).node(), r: den.polynomial(name).node()}
+				}
+			}
+		}
+		return &Expr{op: n.op, l: l, r: r}
+	case *UnaryExpr:
+		return &UnaryExpr{op: n.op, a: cancelNode(n.a)}
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = cancelNode(arg)
+		}
+		return &Func{name: n.name, args: args}
+	case *Piecewise:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = cancelNode(arg)
+		}
+		return &Piecewise{args: args}
+	}
+	return in
+}
+
+// Factor factors the polynomial subexpressions of f with a single variable over the rationals. It extracts the content, splits repeated factors by square-free factorization, and splits off linear factors of rational roots. Factors without rational roots are kept as they are.
+func (f *Function) Factor() {
+	f.root = factorNode(f.root)
+}
+
+// Cancel cancels common factors of the numerator and denominator of divisions of polynomials with a single variable, such as (x^2-1)/(x-1) to x+1.
+func (f *Function) Cancel() {
+	f.root = cancelNode(f.root)
+}
@@ -0,0 +1,83 @@
+package formulae
+
+import "testing"
+
+func TestFactor(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x^2-1", "(x+1)*(x-1)"},
+		{"x^2+2x+1", "(x+1)^2"},
+		{"2x^2-2", "2*(x+1)*(x-1)"},
+		{"1-x^2", "-((x+1)*(x-1))"},
+		{"-2x-2", "-2*(x+1)"},
+		{"x^3-x", "x*(x+1)*(x-1)"},
+		{"x^4-1", "(x+1)*(x-1)*(x^2+1)"},
+		{"x^3+3x^2+3x+1", "(x+1)^3"},
+		{"(x-1)^3*(x+2)", "(x+2)*(x-1)^3"},
+		{"6x^2+x-1", "(2*x+1)*(3*x-1)"},
+		{"4x^2-4x+1", "(2*x-1)^2"},
+		{"0.5x^2-0.5", "0.5*(x+1)*(x-1)"},
+		{"x^2+1", "x^2+1"},
+		{"x^2-2", "x^2-2"},
+		{"sin(x^2-1)", "sin((x+1)*(x-1))"},
+		{"x^2*y-y", "x^2*y-y"},
+		{"x < 0 ? x^2-1 : 0", "piecewise(x<0,(x+1)*(x-1),0)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Factor()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"(x^2-1)/(x-1)", "x+1"},
+		{"(x*1-x*1)/x^2", "0"},
+		{"(x^2+2x+1)/(x+1)^2", "1"},
+		{"(2x+2)/(4x+4)", "0.5"},
+		{"(x^3-x)/(x^2+x)", "x-1"},
+		{"(x^3-x)/(x^2-2x+1)", "(x^2+x)/(x-1)"},
+		{"sin((x^2-1)/(x+1))", "sin(x-1)"},
+		{"x/2", "x/2"},
+		{"(x+1)/(x+2)", "(x+1)/(x+2)"},
+		{"(x^2-y^2)/(x-y)", "(x^2-y^2)/(x-y)"},
+		{"x > 1 ? (x^2-1)/(x-1) : 0", "piecewise(x>1,x+1,0)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Cancel()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestGCDRatPoly(t *testing.T) {
+	f, _ := Parse("x^3-x")
+	g, _ := Parse("2x^2-4x+2")
+	p, _, _ := toPolynomial(f.root, true).toRatPoly()
+	q, _, _ := toPolynomial(g.root, true).toRatPoly()
+	if g := gcdRatPoly(p, q).polynomial("x").node().String(); g != "x-1" {
+		t.Fatal(g, "!=", "x-1")
+	}
+}
@@ -1,8 +1,12 @@
 package formulae
 
 import (
+	"fmt"
 	"math"
-    "fmt"
+	"math/cmplx"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type Vars map[string]complex128
@@ -19,6 +23,30 @@ func (v Vars) Duplicate() Vars {
 	return v2
 }
 
+// varsPool holds empty maps that are reused to bind variables during evaluation, so that evaluating in a loop does not allocate.
+var varsPool = sync.Pool{
+	New: func() interface{} {
+		return Vars{}
+	},
+}
+
+// scratchVars returns a reused copy of v that must be returned by releaseVars after evaluation.
+func scratchVars(v Vars) Vars {
+	scratch := varsPool.Get().(Vars)
+	for key, val := range v {
+		scratch[key] = val
+	}
+	return scratch
+}
+
+// releaseVars clears v and returns it to the pool.
+func releaseVars(v Vars) {
+	for key := range v {
+		delete(v, key)
+	}
+	varsPool.Put(v)
+}
+
 var DefaultVars = Vars{
 	"e":   complex(math.E, 0),
 	"pi":  complex(math.Pi, 0),
@@ -29,57 +57,320 @@ var DefaultVars = Vars{
 
 type Function struct {
 	root Node
-    Vars
-    nthDerivative int
+	Vars
+	nthDerivative int
+	wrt           []string // variables of differentiation in order of application
+	params        []string // parameters bound by Call, nil means x only
+	series        *series  // set for Taylor series
 }
 
 func (f *Function) String() string {
-    return f.root.String()
+	return f.root.String()
 }
 
 func (f *Function) LaTeX() string {
-    d := ""
-    if f.nthDerivative == 1 {
-        d = fmt.Sprintf("\\frac{\\partial}{\\partial x} ")
-    } else if f.nthDerivative > 1 {
-        d = fmt.Sprintf("\\frac{\\partial^{%v}}{\\partial x^{%v}} ", f.nthDerivative, f.nthDerivative)
-    }
-    return fmt.Sprintf("%sf(x) = %s", d, f.root.LaTeX())
+	d := ""
+	if f.nthDerivative == 1 {
+		d = fmt.Sprintf("\\frac{\\partial}{\\partial %s} ", f.wrt[0])
+	} else if f.nthDerivative > 1 {
+		// group consecutive variables, the last applied derivative comes first
+		denom := ""
+		for i := len(f.wrt) - 1; 0 <= i; {
+			j := i
+			for 0 < j && f.wrt[j-1] == f.wrt[i] {
+				j--
+			}
+			if denom != "" {
+				denom += " "
+			}
+			if n := i - j + 1; n == 1 {
+				denom += fmt.Sprintf("\\partial %s", f.wrt[i])
+			} else {
+				denom += fmt.Sprintf("\\partial %s^{%v}", f.wrt[i], n)
+			}
+			i = j - 1
+		}
+		d = fmt.Sprintf("\\frac{\\partial^{%v}}{%s} ", f.nthDerivative, denom)
+	} else if f.nthDerivative < 0 {
+		d = strings.Repeat("\\int ", -f.nthDerivative)
+		return fmt.Sprintf("%sf(%s) %s= %s", d, strings.Join(f.Params(), ", "), strings.Repeat("\\,dx ", -f.nthDerivative), f.root.LaTeX())
+	}
+	if f.series != nil {
+		order := &Expr{op: PowerOp, l: taylorBase(f.series.at), r: &Number{val: complex(float64(f.series.order+1), 0.0)}}
+		return fmt.Sprintf("%sf(%s) = %s+O\\left(%s\\right)", d, strings.Join(f.Params(), ", "), f.root.LaTeX(), order.LaTeX())
+	}
+	return fmt.Sprintf("%sf(%s) = %s", d, strings.Join(f.Params(), ", "), f.root.LaTeX())
+}
+
+// Clone returns a deep copy of f that shares no nodes, variables or parameters with f.
+func (f *Function) Clone() *Function {
+	g := *f
+	g.root = cloneNode(f.root)
+	if f.Vars != nil {
+		g.Vars = f.Vars.Duplicate()
+	}
+	if f.wrt != nil {
+		g.wrt = append([]string{}, f.wrt...)
+	}
+	if f.params != nil {
+		g.params = append([]string{}, f.params...)
+	}
+	if f.series != nil {
+		series := *f.series
+		g.series = &series
+	}
+	return &g
+}
+
+// mergeVars returns the union of the variables of f and g. It returns an error if a variable has different values in f and g.
+func mergeVars(f, g Vars) (Vars, error) {
+	vars := f.Duplicate()
+	for name, val := range g {
+		if prev, ok := vars[name]; ok && prev != val {
+			return nil, fmt.Errorf("variable '%s' has conflicting values %v and %v", name, prev, val)
+		}
+		vars[name] = val
+	}
+	return vars, nil
+}
+
+// Substitute returns f where the variable name is replaced by the expression of expr, and optimizes the result. The variables of f and expr are merged, which returns an error if a variable has different values in both. The result keeps the parameters of f.
+func (f *Function) Substitute(name string, expr *Function) (*Function, error) {
+	vars, err := mergeVars(f.Vars, expr.Vars)
+	if err != nil {
+		return nil, err
+	}
+	return &Function{
+		root:   Optimize(substitute(f.root, name, expr.root)),
+		Vars:   vars,
+		params: f.params,
+	}, nil
+}
+
+// Compose returns the composition f(g(x)), where x in f is replaced by the expression of g, and optimizes the result. The variables of f and g are merged, which returns an error if a variable has different values in both. The result takes the parameters of g.
+func (f *Function) Compose(g *Function) (*Function, error) {
+	h, err := f.Substitute("x", g)
+	if err != nil {
+		return nil, err
+	}
+	h.params = g.params
+	return h, nil
+}
+
+// VariableUse is a variable of a function together with the offsets in the parsed input where it occurs.
+type VariableUse struct {
+	Name    string
+	Pos     []int // offsets of the start of each occurrence, excluding variables that were not parsed
+	Defined bool  // whether it is a parameter or defined in the variables of the function
+}
+
+// Variables returns the variables that occur in f sorted by name. Undefined variables, which are neither parameters nor defined in the variables of f, fail evaluation.
+func (f *Function) Variables() []VariableUse {
+	index := map[string]int{}
+	uses := []VariableUse{}
+	Inspect(f.root, func(n Node) bool {
+		if v, ok := n.(*Variable); ok {
+			i, ok := index[v.name]
+			if !ok {
+				i = len(uses)
+				index[v.name] = i
+				uses = append(uses, VariableUse{Name: v.name, Pos: []int{}})
+			}
+			if v.pos != -1 {
+				uses[i].Pos = append(uses[i].Pos, v.pos)
+			}
+		}
+		return true
+	})
+
+	for i := range uses {
+		_, uses[i].Defined = f.Vars[uses[i].Name]
+		for _, param := range f.Params() {
+			if param == uses[i].Name {
+				uses[i].Defined = true
+			}
+		}
+		sort.Ints(uses[i].Pos)
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		return uses[i].Name < uses[j].Name
+	})
+	return uses
+}
+
+// SetParams declares the parameters of f in the order in which they are passed to Call.
+func (f *Function) SetParams(names ...string) {
+	f.params = append([]string{}, names...)
+}
+
+// Params returns the parameters of f as declared by SetParams, which defaults to x.
+func (f *Function) Params() []string {
+	if f.params == nil {
+		return []string{"x"}
+	}
+	return f.params
 }
 
 func (f *Function) Optimize() {
-    f.root = Optimize(f.root)
+	f.root = Optimize(f.root)
 }
 
+// Derivative returns the derivative of f with respect to x.
 func (f *Function) Derivative() *Function {
-    root := f.root.Derivative()
-    root = Optimize(root)
-    return &Function{
-        root: root,
-        Vars: f.Vars,
-        nthDerivative: f.nthDerivative + 1,
-    }
+	return f.DerivativeWith("x")
+}
+
+// DerivativeWith returns the partial derivative of f with respect to the variable name.
+func (f *Function) DerivativeWith(name string) *Function {
+	root := f.root.Derivative(name)
+	root = Optimize(root)
+	g := &Function{
+		root:          root,
+		Vars:          f.Vars,
+		nthDerivative: f.nthDerivative + 1,
+		params:        f.params,
+	}
+	if f.nthDerivative < 0 {
+		if name != "x" {
+			g.nthDerivative = 1 // cannot be expressed as a sequence of integrals to x
+			g.wrt = []string{name}
+		}
+	} else {
+		g.wrt = append(append(make([]string, 0, len(f.wrt)+1), f.wrt...), name)
+	}
+	return g
+}
+
+// Gradient returns the partial derivatives of f with respect to each of the variables in vars. Equal subexpressions are shared between the derivatives.
+func (f *Function) Gradient(vars ...string) []*Function {
+	grad := make([]*Function, len(vars))
+	for i, name := range vars {
+		grad[i] = f.DerivativeWith(name)
+	}
+	shareFunctions(grad)
+	return grad
+}
+
+// Hessian returns the square matrix of second-order partial derivatives of f with respect to the variables in vars. The second derivatives are derived from the gradient, and by symmetry the entries below the diagonal share their formula with the entries above it.
+func (f *Function) Hessian(vars ...string) [][]*Function {
+	grad := f.Gradient(vars...)
+	hess := make([][]*Function, len(vars))
+	for i := range vars {
+		hess[i] = make([]*Function, len(vars))
+	}
+
+	var fs []*Function
+	for i := range vars {
+		for j := i; j < len(vars); j++ {
+			hess[i][j] = grad[i].DerivativeWith(vars[j])
+			fs = append(fs, hess[i][j])
+		}
+	}
+	shareFunctions(fs)
+	for i := range vars {
+		for j := 0; j < i; j++ {
+			hess[i][j] = &Function{
+				root:          hess[j][i].root,
+				Vars:          f.Vars,
+				nthDerivative: f.nthDerivative + 2,
+				wrt:           append(append(append([]string{}, f.wrt...), vars[i]), vars[j]),
+				params:        f.params,
+			}
+		}
+	}
+	return hess
+}
+
+// Jacobian returns the matrix of partial derivatives of each function in fs (rows) with respect to each of the variables in vars (columns). Equal subexpressions are shared between all entries.
+func Jacobian(fs []*Function, vars ...string) [][]*Function {
+	jac := make([][]*Function, len(fs))
+	all := make([]*Function, 0, len(fs)*len(vars))
+	for i, f := range fs {
+		jac[i] = make([]*Function, len(vars))
+		for j, name := range vars {
+			jac[i][j] = f.DerivativeWith(name)
+		}
+		all = append(all, jac[i]...)
+	}
+	shareFunctions(all)
+	return jac
 }
 
+// shareFunctions replaces structurally equal subexpressions of all functions by a single node, turning the trees into a directed acyclic graph.
+func shareFunctions(fs []*Function) {
+	h := newHashCons()
+	for _, f := range fs {
+		f.root = Rewrite(f.root, func(n Node) Node {
+			n, _ = h.intern(n)
+			return n
+		})
+	}
+}
+
+// Calc evaluates f for the given value of x.
 func (f *Function) Calc(x complex128) (complex128, error) {
-    return f.root.Calc(x, f.Vars)
+	vars := scratchVars(f.Vars)
+	defer releaseVars(vars)
+	vars["x"] = x
+	return f.root.Calc(vars)
+}
+
+// CalcReal evaluates f for the given real value of x using real arithmetic only. It returns a DomainError when the result would not be real, such as for the square root of a negative number.
+func (f *Function) CalcReal(x float64) (float64, error) {
+	vars := scratchVars(f.Vars)
+	defer releaseVars(vars)
+	vars["x"] = complex(x, 0.0)
+	return f.root.CalcReal(vars)
+}
+
+// Eval evaluates f where the variables in vars take precedence over the variables of f.
+func (f *Function) Eval(vars Vars) (complex128, error) {
+	all := scratchVars(f.Vars)
+	defer releaseVars(all)
+	for name, val := range vars {
+		all[name] = val
+	}
+	return f.root.Calc(all)
 }
 
+// Call evaluates f for the given arguments, which are bound in order to the parameters of f.
+func (f *Function) Call(args ...complex128) (complex128, error) {
+	params := f.Params()
+	if len(args) != len(params) {
+		return cmplx.NaN(), fmt.Errorf("function takes %d arguments, got %d", len(params), len(args))
+	}
+
+	vars := scratchVars(f.Vars)
+	defer releaseVars(vars)
+	for i, name := range params {
+		vars[name] = args[i]
+	}
+	return f.root.Calc(vars)
+}
+
+// Interval evaluates f for x from xMin to xMax with step size xStep, and returns an error for each sample that failed. It compiles f once so that each sample is cheap to evaluate, and falls back to Calc if f cannot be compiled.
 func (f *Function) Interval(xMin, xStep, xMax float64) ([]float64, []complex128, []error) {
 	n := int((xMax-xMin)/xStep) + 1
 	xs := make([]float64, n)
 	ys := make([]complex128, n)
 
+	calc := f.Calc
+	if p, err := f.compile([]string{"x"}); err == nil {
+		calc = func(x complex128) (complex128, error) {
+			return p.Call(x)
+		}
+	}
+
 	x := xMin
-    var err error
-    var errs []error
-    for i := 0; i < n; i++ {
-        xs[i] = x
-        ys[i], err = f.Calc(complex(x, 0))
-        if err != nil {
-            errs = append(errs, err)
-        }
-        x += xStep
-    }
+	var err error
+	var errs []error
+	for i := 0; i < n; i++ {
+		xs[i] = x
+		ys[i], err = calc(complex(x, 0))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		x += xStep
+	}
 	return xs, ys, errs
 }
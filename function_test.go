@@ -1,6 +1,8 @@
 package formulae
 
 import (
+	"fmt"
+	"math"
 	"math/cmplx"
 	"testing"
 )
@@ -20,6 +22,28 @@ func TestCalc(t *testing.T) {
 		{"LN(E)", 1},
 		{"5+1i+6+2i", 11 + 3i},
 		{"i", 1i},
+		{"atan2(1, 1)", 0.7853981633974483},
+		{"max(1, x, 3)", 5},
+		{"min(1, x, 3)", 1},
+		{"log(2, 8)", 3},
+		{"pow(x, 2)", 25},
+		{"cbrt(-8)", -2},
+		{"log2(8)", 3},
+		{"erf(0.5)", 0.5204998778130465},
+		{"erf(1+1i)", 1.3161512816979476 + 0.1904534692378347i},
+		{"gamma(x)", 24},
+		{"gamma(1+1i)", 0.4980156681183560 - 0.1549498283018106i},
+		{"digamma(1)", -0.5772156649015329},
+		{"polygamma(1, 1)", 1.6449340668482264},
+		{"|3-x|", 2},
+		{"|3+4i|", 5},
+		{"x!", 120},
+		{"0.5!", 0.8862269254527580},
+		{"1i!", 0.4980156681183560 - 0.1549498283018106i},
+		{"x%3", 2},
+		{"-x%3", 1},
+		{"x%-3", -1},
+		{"mod(7.5, x)", 2.5},
 	}
 
 	x := 5 + 0i
@@ -48,6 +72,12 @@ func TestCalcErr(t *testing.T) {
 	}{
 		{"4y", "undefined variable 'y'"},
 		{"3/(5-x)", "division by zero"},
+		{"(-x)!", "factorial of negative integer"},
+		{"gamma(x-5)", "gamma of non-positive integer"},
+		{"digamma(-x)", "digamma of non-positive integer"},
+		{"polygamma(1, -x)", "polygamma of non-positive integer"},
+		{"x%(5-x)", "modulo by zero"},
+		{"1i%x", "modulo of complex numbers"},
 	}
 
 	x := 5 + 0i
@@ -96,3 +126,371 @@ func BenchmarkCalc(b *testing.B) {
         }
     }
 }
+
+func TestCalcAllocs(t *testing.T) {
+	f, _ := Parse("x*pi+a")
+	f.Vars.Set("a", 1.0)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := f.Calc(2.0); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatal(allocs, "!=", 0)
+	}
+}
+
+func TestLaTeX(t *testing.T) {
+	f, _ := Parse("x^2*y")
+	g, _ := Parse("|x-1|+(x+1)!%3")
+	tests := []struct {
+		f   *Function
+		out string
+	}{
+		{f, "f(x) = x^{2} y"},
+		{f.Derivative(), "\\frac{\\partial}{\\partial x} f(x) = y 2 x"},
+		{f.DerivativeWith("y"), "\\frac{\\partial}{\\partial y} f(x) = x^{2}"},
+		{f.Derivative().Derivative(), "\\frac{\\partial^{2}}{\\partial x^{2}} f(x) = 2 y"},
+		{f.Derivative().DerivativeWith("y"), "\\frac{\\partial^{2}}{\\partial y \\partial x} f(x) = 2 x"},
+		{f.Derivative().DerivativeWith("y").DerivativeWith("y"), "\\frac{\\partial^{3}}{\\partial y^{2} \\partial x} f(x) = 0"},
+		{g, "f(x) = \\left|x-1\\right|+\\left(x+1\\right)! \\bmod 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.out, func(t *testing.T) {
+			if test.f.LaTeX() != test.out {
+				t.Fatal(test.f.LaTeX(), "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestGradient(t *testing.T) {
+	f, _ := Parse("x^2*y+y^3")
+	f.Vars.Set("y", 2)
+
+	grad := f.Gradient("x", "y")
+	out := []complex128{20, 37}
+	for i := range out {
+		if y, err := grad[i].Calc(5); err != nil {
+			t.Fatal(err)
+		} else if cmplx.Abs(y-out[i]) > Epsilon {
+			t.Fatal(i, y, "!=", out[i])
+		}
+	}
+
+	hess := f.Hessian("x", "y")
+	hessOut := [][]complex128{{4, 10}, {10, 12}}
+	for i := range hessOut {
+		for j := range hessOut[i] {
+			if y, err := hess[i][j].Calc(5); err != nil {
+				t.Fatal(err)
+			} else if cmplx.Abs(y-hessOut[i][j]) > Epsilon {
+				t.Fatal(i, j, y, "!=", hessOut[i][j])
+			}
+		}
+	}
+	if hess[0][1].root != hess[1][0].root {
+		t.Fatal("symmetric Hessian entries are not shared")
+	}
+}
+
+func TestJacobian(t *testing.T) {
+	f, _ := Parse("x*y")
+	g, _ := Parse("sin(x)+y")
+	f.Vars.Set("y", 2)
+	g.Vars.Set("y", 2)
+
+	jac := Jacobian([]*Function{f, g}, "x", "y")
+	out := [][]complex128{{2, 5}, {cmplx.Cos(5), 1}}
+	for i := range out {
+		for j := range out[i] {
+			if y, err := jac[i][j].Calc(5); err != nil {
+				t.Fatal(err)
+			} else if cmplx.Abs(y-out[i][j]) > Epsilon {
+				t.Fatal(i, j, y, "!=", out[i][j])
+			}
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	f, _ := Parse("x*y+t")
+	y, err := f.Eval(Vars{"x": 2, "y": 3, "t": 1})
+	if err != nil {
+		t.Fatal(err)
+	} else if y != 7 {
+		t.Fatal(y, "!=", 7)
+	}
+
+	if _, err := f.Eval(Vars{"y": 3, "t": 1}); err == nil || err.Error() != "undefined variable 'x'" {
+		t.Fatal(err, "!=", "undefined variable 'x'")
+	}
+}
+
+func TestCall(t *testing.T) {
+	f, _ := Parse("x*y+t")
+	f.SetParams("x", "y", "t")
+	y, err := f.Call(2, 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if y != 7 {
+		t.Fatal(y, "!=", 7)
+	}
+
+	if _, err := f.Call(2, 3); err == nil || err.Error() != "function takes 3 arguments, got 2" {
+		t.Fatal(err, "!=", "function takes 3 arguments, got 2")
+	}
+	if latex := f.LaTeX(); latex != "f(x, y, t) = x y+t" {
+		t.Fatal(latex, "!=", "f(x, y, t) = x y+t")
+	}
+}
+
+func TestCalcReal(t *testing.T) {
+	tests := []struct {
+		in  string
+		out float64
+	}{
+		{"1+2*3", 7},
+		{"(-2)^2", 4},
+		{"(-x)^3", -125},
+		{"sqrt(x-1)", 2},
+		{"log(x-4)", 0},
+		{"log(5, x)", 1},
+		{"max(x, 1, 7)", 7},
+		{"erf(0)+cbrt(-8)", -2},
+		{"gamma(x)", 24},
+		{"|-x|", 5},
+		{"x!", 120},
+		{"(-0.5)!", 1.7724538509055159},
+		{"-x%3", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			function, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(function, errs)
+			}
+
+			y, err := function.CalcReal(5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(y-test.out) > Epsilon {
+				t.Fatal(y, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestCalcRealErr(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{"sqrt(-x)", "sqrt of negative number"},
+		{"log(-x)", "log of negative number"},
+		{"arcsin(x)", "arcsin of number outside [-1,1]"},
+		{"(-x)^0.5", "non-integer power of negative number"},
+		{"x+1i", "number (0+1i) is not real"},
+		{"3/(5-x)", "division by zero"},
+		{"(-x)!", "factorial of negative integer"},
+		{"gamma(-x)", "gamma of non-positive integer"},
+		{"x%0", "modulo by zero"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			function, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(function, errs)
+			}
+
+			_, err := function.CalcReal(5)
+			if err == nil {
+				t.Fatal("nil !=", test.err)
+			} else if err.Error() != test.err {
+				t.Fatal(err.Error(), "!=", test.err)
+			}
+		})
+	}
+}
+
+func TestDomainError(t *testing.T) {
+	tests := []struct {
+		in string
+		op string
+	}{
+		{"sqrt(-x)", "sqrt"},
+		{"(-x)^0.5", "^"},
+		{"gamma(-x)", "gamma"},
+		{"(-x)!", "!"},
+		{"x+1i", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			function, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(function, errs)
+			}
+
+			_, err := function.CalcReal(5)
+			if domainErr, ok := err.(DomainError); !ok {
+				t.Fatal(err, "is not a DomainError")
+			} else if domainErr.Op != test.op {
+				t.Fatal(domainErr.Op, "!=", test.op)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	f, errs := Parse("(x+0)*2+a")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f.Vars = Vars{"a": 1.0}
+	f.SetParams("x", "a")
+
+	g := f.Clone()
+	g.Optimize()
+	g.Expand()
+	g.Vars.Set("a", 2.0)
+	g.SetParams("x")
+	if f.String() != "(x+0)*2+a" {
+		t.Fatal(f, "!=", "(x+0)*2+a")
+	} else if g.String() != "a+2*x" {
+		t.Fatal(g, "!=", "a+2*x")
+	} else if f.Vars["a"] != 1.0 {
+		t.Fatal(f.Vars["a"], "!=", 1.0)
+	} else if len(f.Params()) != 2 {
+		t.Fatal(f.Params(), "!=", []string{"x", "a"})
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	f, errs := Parse("sin(x*2)^2*y+1*x")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f.Vars = Vars{"y": 3.0}
+
+	want := f.String()
+	done := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() {
+			df := f.Derivative()
+			df.Optimize()
+			f.DerivativeWith("y").Hessian("x", "y")
+			_, err := f.Calc(0.5)
+			done <- err
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.String() != want {
+		t.Fatal(f, "!=", want)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		f, name, expr string
+		out           string
+	}{
+		{"x^2+y", "y", "sin(x)", "x^2+sin(x)"},
+		{"x^2+y", "x", "y+1", "(y+1)^2+y"},
+		{"a*x", "a", "0", "0"},
+		{"sin(x)", "z", "2", "sin(x)"},
+		{"x*2", "x", "3", "6"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.f, func(t *testing.T) {
+			f, errs := Parse(test.f)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			expr, errs := Parse(test.expr)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			g, err := f.Substitute(test.name, expr)
+			if err != nil {
+				t.Fatal(err)
+			} else if g.String() != test.out {
+				t.Fatal(g, "!=", test.out)
+			} else if f.String() != test.f {
+				t.Fatal(f, "!=", test.f)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	f, errs := Parse("sin(x)+a")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f.Vars.Set("a", 1.0)
+	g, errs := Parse("x^2*b")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	g.Vars.Set("b", 2.0)
+	g.SetParams("x", "c")
+
+	h, err := f.Compose(g)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.String() != "sin(x^2*b)+a" {
+		t.Fatal(h, "!=", "sin(x^2*b)+a")
+	} else if len(h.Params()) != 2 {
+		t.Fatal(h.Params(), "!=", []string{"x", "c"})
+	}
+
+	y, err := h.Calc(3.0)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := complex(math.Sin(18.0)+1.0, 0.0); y != want {
+		t.Fatal(y, "!=", want)
+	}
+
+	g.Vars.Set("a", 2.0)
+	if _, err := f.Compose(g); err == nil || err.Error() != "variable 'a' has conflicting values (1+0i) and (2+0i)" {
+		t.Fatal(err, "!=", "variable 'a' has conflicting values (1+0i) and (2+0i)")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	f, errs := Parse("a*x + sin(2x) - b^a")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f.Vars.Set("a", 1.0)
+
+	want := []VariableUse{
+		{"a", []int{0, 18}, true},
+		{"b", []int{16}, false},
+		{"x", []int{2, 11}, true},
+	}
+	uses := f.Variables()
+	if len(uses) != len(want) {
+		t.Fatal(uses, "!=", want)
+	}
+	for i := range uses {
+		if uses[i].Name != want[i].Name || uses[i].Defined != want[i].Defined || fmt.Sprint(uses[i].Pos) != fmt.Sprint(want[i].Pos) {
+			t.Fatal(uses[i], "!=", want[i])
+		}
+	}
+
+	df := f.Derivative()
+	if uses := df.Variables(); len(uses) != 2 || uses[0].Name != "a" || uses[1].Name != "x" {
+		t.Fatal(uses, "!=", "a and x")
+	}
+}
@@ -8,51 +8,69 @@ type Hash uint32
 
 // Identifiers for the hashes associated with the text in the comments.
 const (
-	Arccos  Hash = 0x1c06 // arccos
-	Arccosh Hash = 0x1c07 // arccosh
-	Arcsin  Hash = 0x6    // arcsin
-	Arcsinh Hash = 0x7    // arcsinh
-	Arctan  Hash = 0x706  // arctan
-	Arctanh Hash = 0x707  // arctanh
-	Cbrt    Hash = 0xe04  // cbrt
-	Cos     Hash = 0x1f03 // cos
-	Cosh    Hash = 0x1f04 // cosh
-	Erf     Hash = 0x1203 // erf
-	Exp     Hash = 0x1503 // exp
-	Gamma   Hash = 0x1805 // gamma
-	Ln      Hash = 0x2302 // ln
-	Log     Hash = 0x2503 // log
-	Log10   Hash = 0x2505 // log10
-	Log2    Hash = 0x2a04 // log2
-	Sin     Hash = 0x303  // sin
-	Sinh    Hash = 0x304  // sinh
-	Sqrt    Hash = 0x2e04 // sqrt
-	Tan     Hash = 0xa03  // tan
-	Tanh    Hash = 0xa04  // tanh
+	Abs       Hash = 0x4203 // abs
+	Arccos    Hash = 0x1106 // arccos
+	Arccosh   Hash = 0x1107 // arccosh
+	Arcsin    Hash = 0x1806 // arcsin
+	Arcsinh   Hash = 0x1807 // arcsinh
+	Arctan    Hash = 0x1f06 // arctan
+	Arctanh   Hash = 0x1f07 // arctanh
+	Atan2     Hash = 0x2c05 // atan2
+	Cbrt      Hash = 0x3604 // cbrt
+	Cos       Hash = 0x1403 // cos
+	Cosh      Hash = 0x1404 // cosh
+	Digamma   Hash = 0x2607 // digamma
+	Erf       Hash = 0x4503 // erf
+	Exp       Hash = 0x4803 // exp
+	Gamma     Hash = 0xd05  // gamma
+	Ln        Hash = 0x5702 // ln
+	Log       Hash = 0x3103 // log
+	Log10     Hash = 0x3105 // log10
+	Log2      Hash = 0x3a04 // log2
+	Max       Hash = 0x4b03 // max
+	Min       Hash = 0x4e03 // min
+	Mod       Hash = 0x5103 // mod
+	Piecewise Hash = 0x9    // piecewise
+	Polygamma Hash = 0x909  // polygamma
+	Pow       Hash = 0x5403 // pow
+	Sin       Hash = 0x1b03 // sin
+	Sinh      Hash = 0x1b04 // sinh
+	Sqrt      Hash = 0x3e04 // sqrt
+	Tan       Hash = 0x2203 // tan
+	Tanh      Hash = 0x2204 // tanh
 )
 
 var HashMap = map[string]Hash{
-	"arccos":  Arccos,
-	"arccosh": Arccosh,
-	"arcsin":  Arcsin,
-	"arcsinh": Arcsinh,
-	"arctan":  Arctan,
-	"arctanh": Arctanh,
-	"cbrt":    Cbrt,
-	"cos":     Cos,
-	"cosh":    Cosh,
-	"erf":     Erf,
-	"exp":     Exp,
-	"gamma":   Gamma,
-	"ln":      Ln,
-	"log":     Log,
-	"log10":   Log10,
-	"log2":    Log2,
-	"sin":     Sin,
-	"sinh":    Sinh,
-	"sqrt":    Sqrt,
-	"tan":     Tan,
-	"tanh":    Tanh,
+	"abs":       Abs,
+	"arccos":    Arccos,
+	"arccosh":   Arccosh,
+	"arcsin":    Arcsin,
+	"arcsinh":   Arcsinh,
+	"arctan":    Arctan,
+	"arctanh":   Arctanh,
+	"atan2":     Atan2,
+	"cbrt":      Cbrt,
+	"cos":       Cos,
+	"cosh":      Cosh,
+	"digamma":   Digamma,
+	"erf":       Erf,
+	"exp":       Exp,
+	"gamma":     Gamma,
+	"ln":        Ln,
+	"log":       Log,
+	"log10":     Log10,
+	"log2":      Log2,
+	"max":       Max,
+	"min":       Min,
+	"mod":       Mod,
+	"piecewise": Piecewise,
+	"polygamma": Polygamma,
+	"pow":       Pow,
+	"sin":       Sin,
+	"sinh":      Sinh,
+	"sqrt":      Sqrt,
+	"tan":       Tan,
+	"tanh":      Tanh,
 }
 
 // String returns the text associated with the hash.
@@ -103,31 +121,40 @@ NEXT:
 }
 
 const _Hash_hash0 = 0xf0c5341e
-const _Hash_maxLen = 7
+const _Hash_maxLen = 9
 
 var _Hash_text = []byte("" +
-	"arcsinharctanhcbrterfexpgammarccoshlnlog10log2sqrt")
+	"piecewisepolygammarccosharcsinharctanhdigammatan2log10cbrtlog2sqrtabserfexpmaxminmodpowln")
 
-var _Hash_table = [1 << 5]Hash{
-	0x0:  0x7,    // arcsinh
-	0x1:  0x1f04, // cosh
-	0x3:  0xa04,  // tanh
-	0x4:  0x2302, // ln
-	0x5:  0x1503, // exp
-	0x7:  0x1805, // gamma
-	0x8:  0x6,    // arcsin
-	0x9:  0x2505, // log10
-	0xd:  0x1c06, // arccos
-	0xe:  0x2e04, // sqrt
-	0xf:  0x707,  // arctanh
-	0x11: 0x1f03, // cos
-	0x12: 0x2a04, // log2
-	0x14: 0x2503, // log
-	0x16: 0x1203, // erf
-	0x19: 0xa03,  // tan
-	0x1b: 0x303,  // sin
-	0x1c: 0x304,  // sinh
-	0x1d: 0x706,  // arctan
-	0x1e: 0xe04,  // cbrt
-	0x1f: 0x1c07, // arccosh
+var _Hash_table = [1 << 6]Hash{
+	0x0:  0x4b03, // max
+	0x2:  0x5103, // mod
+	0x3:  0x2204, // tanh
+	0x4:  0x5702, // ln
+	0x8:  0x1806, // arcsin
+	0xa:  0x4e03, // min
+	0xf:  0x1f07, // arctanh
+	0x11: 0x1403, // cos
+	0x16: 0x2c05, // atan2
+	0x1b: 0x2203, // tan
+	0x1c: 0x1b03, // sin
+	0x1d: 0x4503, // erf
+	0x1f: 0x1107, // arccosh
+	0x20: 0x1807, // arcsinh
+	0x22: 0x2607, // digamma
+	0x27: 0xd05,  // gamma
+	0x29: 0x3105, // log10
+	0x2a: 0x4203, // abs
+	0x2d: 0x1106, // arccos
+	0x2e: 0x909,  // polygamma
+	0x2f: 0x1b04, // sinh
+	0x30: 0x9,    // piecewise
+	0x31: 0x4803, // exp
+	0x32: 0x3a04, // log2
+	0x34: 0x3103, // log
+	0x38: 0x5403, // pow
+	0x39: 0x3604, // cbrt
+	0x3b: 0x1404, // cosh
+	0x3c: 0x3e04, // sqrt
+	0x3d: 0x1f06, // arctan
 }
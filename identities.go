@@ -0,0 +1,399 @@
+package formulae
+
+import (
+	"github.com/tdewolff/formulae/hash"
+)
+
+// maxIdentityPasses limits the number of passes over the tree when applying identities.
+const maxIdentityPasses = 16
+
+// Identities is the direction in which Simplify applies identities.
+type Identities int
+
+const (
+	// ContractIdentities combines functions, such as log(a)+log(b) to log(a*b) and sin(a)^2+cos(a)^2 to 1.
+	ContractIdentities Identities = iota
+	// ExpandIdentities splits functions of sums, products and multiples, such as log(a*b) to log(a)+log(b) and sin(2*a) to 2*sin(a)*cos(a).
+	ExpandIdentities
+)
+
+// Simplify applies the identities of the trigonometric, hyperbolic, exponential and logarithmic functions in the given direction until no more apply. The identities hold formally and do not take branch cuts into account, for example log(a*b) = log(a)+log(b) does not hold for all complex a and b.
+func (f *Function) Simplify(dir Identities) {
+	root := Optimize(f.root)
+	for i := 0; i < maxIdentityPasses; i++ {
+		prev := root.String()
+		root = Optimize(applyIdentities(root, dir))
+		if root.String() == prev {
+			break
+		}
+	}
+	f.root = root
+}
+
+// applyIdentities applies identities bottom-up in a single pass.
+func applyIdentities(in Node, dir Identities) Node {
+	switch n := in.(type) {
+	case *Expr:
+		in = &Expr{op: n.op, l: applyIdentities(n.l, dir), r: applyIdentities(n.r, dir)}
+	case *UnaryExpr:
+		in = &UnaryExpr{op: n.op, a: applyIdentities(n.a, dir)}
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = applyIdentities(arg, dir)
+		}
+		in = &Func{name: n.name, args: args}
+	case *Piecewise:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = applyIdentities(arg, dir)
+		}
+		in = &Piecewise{args: args}
+	}
+
+	if dir == ExpandIdentities {
+		return expandIdentity(in)
+	}
+	return contractIdentity(in)
+}
+
+func newFunc(name hash.Hash, arg Node) Node {
+	return &Func{name: name, args: []Node{arg}}
+}
+
+func newExp(a Node) Node {
+	return &Expr{op: PowerOp, l: &Variable{name: "e", pos: -1}, r: a}
+}
+
+// funcArg returns the argument of n if it is a function of one argument with the given name.
+func funcArg(n Node, name hash.Hash) (Node, bool) {
+	if nFunc, ok := n.(*Func); ok && nFunc.name == name && len(nFunc.args) == 1 {
+		return nFunc.args[0], true
+	}
+	return nil, false
+}
+
+// expArg returns the exponent of n if it is a power of e.
+func expArg(n Node) (Node, bool) {
+	if nExpr, ok := n.(*Expr); ok && nExpr.op == PowerOp {
+		if lVariable, ok := nExpr.l.(*Variable); ok && lVariable.name == "e" {
+			return nExpr.r, true
+		}
+	}
+	return nil, false
+}
+
+// squareArg returns the argument of n if it is the square of a function of one argument with the given name.
+func squareArg(n Node, name hash.Hash) (Node, bool) {
+	if nExpr, ok := n.(*Expr); ok && nExpr.op == PowerOp && nExpr.r.Equal(TwoNode) {
+		return funcArg(nExpr.l, name)
+	}
+	return nil, false
+}
+
+// doubleArg returns a if n is 2*a.
+func doubleArg(n Node) (Node, bool) {
+	if nExpr, ok := n.(*Expr); ok && nExpr.op == MultiplyOp {
+		if nExpr.l.Equal(TwoNode) {
+			return nExpr.r, true
+		} else if nExpr.r.Equal(TwoNode) {
+			return nExpr.l, true
+		}
+	}
+	return nil, false
+}
+
+// sumArgs returns a and b if n is a+b or a-b.
+func sumArgs(n Node) (Node, Node, Operator, bool) {
+	if nExpr, ok := n.(*Expr); ok && (nExpr.op == AddOp || nExpr.op == SubtractOp) {
+		return nExpr.l, nExpr.r, nExpr.op, true
+	}
+	return nil, nil, 0, false
+}
+
+// angleSum holds the functions of the addition theorem f(a±b) = g(a)h(b) ± k(a)l(b), where the sign of the right-hand side is flipped if negated.
+type angleSum struct {
+	f, g, h, k, l hash.Hash
+	negated       bool
+}
+
+var angleSums = []angleSum{
+	{hash.Sin, hash.Sin, hash.Cos, hash.Cos, hash.Sin, false},
+	{hash.Cos, hash.Cos, hash.Cos, hash.Sin, hash.Sin, true},
+	{hash.Sinh, hash.Sinh, hash.Cosh, hash.Cosh, hash.Sinh, false},
+	{hash.Cosh, hash.Cosh, hash.Cosh, hash.Sinh, hash.Sinh, false},
+}
+
+func newMul(l, r Node) Node {
+	return &Expr{op: MultiplyOp, l: l, r: r}
+}
+
+func expandIdentity(in Node) Node {
+	if a, ok := expArg(in); ok {
+		// e^(a+b) = e^a*e^b and e^(a-b) = e^a/e^b
+		if l, r, op, ok := sumArgs(a); ok {
+			if op == AddOp {
+				return newMul(newExp(l), newExp(r))
+			}
+			return &Expr{op: DivideOp, l: newExp(l), r: newExp(r)}
+		}
+		return in
+	}
+
+	n, ok := in.(*Func)
+	if !ok || len(n.args) != 1 {
+		return in
+	}
+	a := n.args[0]
+	switch n.name {
+	case hash.Log, hash.Log10, hash.Log2:
+		if aExpr, ok := a.(*Expr); ok {
+			switch aExpr.op {
+			case MultiplyOp:
+				return &Expr{op: AddOp, l: newFunc(n.name, aExpr.l), r: newFunc(n.name, aExpr.r)}
+			case DivideOp:
+				return &Expr{op: SubtractOp, l: newFunc(n.name, aExpr.l), r: newFunc(n.name, aExpr.r)}
+			case PowerOp:
+				return newMul(aExpr.r, newFunc(n.name, aExpr.l))
+			}
+		}
+	case hash.Tan:
+		return &Expr{op: DivideOp, l: newFunc(hash.Sin, a), r: newFunc(hash.Cos, a)}
+	case hash.Tanh:
+		return &Expr{op: DivideOp, l: newFunc(hash.Sinh, a), r: newFunc(hash.Cosh, a)}
+	}
+
+	for _, s := range angleSums {
+		if n.name != s.f {
+			continue
+		} else if l, r, op, ok := sumArgs(a); ok {
+			if s.negated == (op == AddOp) {
+				op = SubtractOp
+			} else {
+				op = AddOp
+			}
+			return &Expr{
+				op: op,
+				l:  newMul(newFunc(s.g, l), newFunc(s.h, r)),
+				r:  newMul(newFunc(s.k, l), newFunc(s.l, r)),
+			}
+		} else if b, ok := doubleArg(a); ok {
+			switch n.name {
+			case hash.Sin, hash.Sinh:
+				// sin(2a) = 2*sin(a)*cos(a)
+				return newMul(newMul(TwoNode, newFunc(s.g, b)), newFunc(s.h, b))
+			case hash.Cos:
+				// cos(2a) = cos(a)^2-sin(a)^2
+				return &Expr{op: SubtractOp, l: newSquare(newFunc(hash.Cos, b)), r: newSquare(newFunc(hash.Sin, b))}
+			case hash.Cosh:
+				// cosh(2a) = cosh(a)^2+sinh(a)^2
+				return &Expr{op: AddOp, l: newSquare(newFunc(hash.Cosh, b)), r: newSquare(newFunc(hash.Sinh, b))}
+			}
+		}
+	}
+	return in
+}
+
+func newSquare(n Node) Node {
+	return &Expr{op: PowerOp, l: n, r: TwoNode}
+}
+
+// signedTerm is a term of a sum.
+type signedTerm struct {
+	n   Node
+	neg bool
+}
+
+// sumTerms flattens additions, subtractions and negations into a list of terms.
+func sumTerms(in Node, neg bool, terms []signedTerm) []signedTerm {
+	if n, ok := in.(*Expr); ok && (n.op == AddOp || n.op == SubtractOp) {
+		terms = sumTerms(n.l, neg, terms)
+		return sumTerms(n.r, neg != (n.op == SubtractOp), terms)
+	} else if n, ok := in.(*UnaryExpr); ok && n.op == MinusOp {
+		return sumTerms(n.a, !neg, terms)
+	}
+	return append(terms, signedTerm{in, neg})
+}
+
+func buildSum(terms []signedTerm) Node {
+	var root Node
+	for _, t := range terms {
+		if root == nil {
+			root = t.n
+			if t.neg {
+				root = negateNode(root)
+			}
+		} else if t.neg {
+			root = &Expr{op: SubtractOp, l: root, r: t.n}
+		} else {
+			root = &Expr{op: AddOp, l: root, r: t.n}
+		}
+	}
+	if root == nil {
+		return ZeroNode
+	}
+	return root
+}
+
+// productFactors flattens multiplications into a list of factors.
+func productFactors(in Node, factors []Node) []Node {
+	if n, ok := in.(*Expr); ok && n.op == MultiplyOp {
+		factors = productFactors(n.l, factors)
+		return productFactors(n.r, factors)
+	}
+	return append(factors, in)
+}
+
+func contractIdentity(in Node) Node {
+	switch n := in.(type) {
+	case *Expr:
+		switch n.op {
+		case AddOp, SubtractOp:
+			terms := sumTerms(n, false, nil)
+			for i := 0; i < len(terms); i++ {
+				for j := i + 1; j < len(terms); j++ {
+					if t, ok := contractTerms(terms[i], terms[j]); ok {
+						terms[i] = t
+						terms = append(terms[:j], terms[j+1:]...)
+						return buildSum(terms)
+					}
+				}
+			}
+		case MultiplyOp:
+			factors := productFactors(n, nil)
+			for i := 0; i < len(factors); i++ {
+				for j := i + 1; j < len(factors); j++ {
+					if a, ok := expArg(factors[i]); ok {
+						if b, ok := expArg(factors[j]); ok {
+							// e^a*e^b = e^(a+b)
+							factors[i] = newExp(&Expr{op: AddOp, l: a, r: b})
+							factors = append(factors[:j], factors[j+1:]...)
+							return multiplyNodes(factors)
+						}
+					}
+				}
+			}
+			for _, s := range [][2]hash.Hash{{hash.Sin, hash.Cos}, {hash.Sinh, hash.Cosh}} {
+				// 2*sin(a)*cos(a) = sin(2a)
+				two, sin, cos := -1, -1, -1
+				for i, factor := range factors {
+					if factor.Equal(TwoNode) {
+						two = i
+					} else if _, ok := funcArg(factor, s[0]); ok {
+						sin = i
+					} else if _, ok := funcArg(factor, s[1]); ok {
+						cos = i
+					}
+				}
+				if two != -1 && sin != -1 && cos != -1 {
+					a, _ := funcArg(factors[sin], s[0])
+					if b, _ := funcArg(factors[cos], s[1]); a.Equal(b) {
+						rest := []Node{newFunc(s[0], newMul(TwoNode, a))}
+						for i, factor := range factors {
+							if i != two && i != sin && i != cos {
+								rest = append(rest, factor)
+							}
+						}
+						return multiplyNodes(rest)
+					}
+				}
+			}
+			if lNumber, ok := n.l.(*Number); ok && imag(lNumber.val) == 0.0 {
+				// c*log(a) = log(a^c)
+				for _, name := range []hash.Hash{hash.Log, hash.Log10, hash.Log2} {
+					if a, ok := funcArg(n.r, name); ok {
+						return newFunc(name, &Expr{op: PowerOp, l: a, r: n.l})
+					}
+				}
+			}
+		case DivideOp:
+			if a, ok := expArg(n.l); ok {
+				if b, ok := expArg(n.r); ok {
+					// e^a/e^b = e^(a-b)
+					return newExp(&Expr{op: SubtractOp, l: a, r: b})
+				}
+			}
+			for _, s := range [][3]hash.Hash{{hash.Sin, hash.Cos, hash.Tan}, {hash.Sinh, hash.Cosh, hash.Tanh}} {
+				// sin(a)/cos(a) = tan(a)
+				if a, ok := funcArg(n.l, s[0]); ok {
+					if b, ok := funcArg(n.r, s[1]); ok && a.Equal(b) {
+						return newFunc(s[2], a)
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+// contractTerms combines two terms of a sum into one.
+func contractTerms(t, u signedTerm) (signedTerm, bool) {
+	for k := 0; k < 2; k++ {
+		// sin(a)^2+cos(a)^2 = 1
+		if a, ok := squareArg(t.n, hash.Sin); ok && t.neg == u.neg {
+			if b, ok := squareArg(u.n, hash.Cos); ok && a.Equal(b) {
+				return signedTerm{OneNode, t.neg}, true
+			}
+		}
+		if a, ok := squareArg(t.n, hash.Cos); ok && t.neg != u.neg {
+			// cos(a)^2-sin(a)^2 = cos(2a)
+			if b, ok := squareArg(u.n, hash.Sin); ok && a.Equal(b) {
+				return signedTerm{newFunc(hash.Cos, newMul(TwoNode, a)), t.neg}, true
+			}
+		}
+		if a, ok := squareArg(t.n, hash.Cosh); ok {
+			if b, ok := squareArg(u.n, hash.Sinh); ok && a.Equal(b) {
+				if t.neg != u.neg {
+					// cosh(a)^2-sinh(a)^2 = 1
+					return signedTerm{OneNode, t.neg}, true
+				}
+				// cosh(a)^2+sinh(a)^2 = cosh(2a)
+				return signedTerm{newFunc(hash.Cosh, newMul(TwoNode, a)), t.neg}, true
+			}
+		}
+
+		for _, name := range []hash.Hash{hash.Log, hash.Log10, hash.Log2} {
+			if a, ok := funcArg(t.n, name); ok && !t.neg {
+				if b, ok := funcArg(u.n, name); ok {
+					// log(a)+log(b) = log(a*b) and log(a)-log(b) = log(a/b)
+					op := MultiplyOp
+					if u.neg {
+						op = DivideOp
+					}
+					return signedTerm{newFunc(name, &Expr{op: op, l: a, r: b}), false}, true
+				}
+			}
+		}
+
+		// addition theorems, such as sin(a)*cos(b)+cos(a)*sin(b) = sin(a+b)
+		tf := productFactors(t.n, nil)
+		uf := productFactors(u.n, nil)
+		if len(tf) == 2 && len(uf) == 2 {
+			for _, s := range angleSums {
+				for _, tf := range [][]Node{tf, {tf[1], tf[0]}} {
+					a, ok1 := funcArg(tf[0], s.g)
+					b, ok2 := funcArg(tf[1], s.h)
+					if !ok1 || !ok2 {
+						continue
+					}
+					for _, uf := range [][]Node{uf, {uf[1], uf[0]}} {
+						c, ok1 := funcArg(uf[0], s.k)
+						d, ok2 := funcArg(uf[1], s.l)
+						if !ok1 || !ok2 || t.neg {
+							continue
+						}
+						op := AddOp
+						if u.neg != s.negated {
+							op = SubtractOp
+						}
+						if a.Equal(c) && b.Equal(d) {
+							return signedTerm{newFunc(s.f, &Expr{op: op, l: a, r: b}), false}, true
+						}
+					}
+				}
+			}
+		}
+		t, u = u, t
+	}
+	return signedTerm{}, false
+}
@@ -0,0 +1,103 @@
+package formulae
+
+import "testing"
+
+func TestSimplifyExpand(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"ln(x*y)", "log(x)+log(y)"},
+		{"ln(x/y)", "log(x)-log(y)"},
+		{"ln(x^2)", "2*log(x)"},
+		{"log10(x*y)", "log10(x)+log10(y)"},
+		{"sin(2x)", "2*sin(x)*cos(x)"},
+		{"cos(2x)", "cos(x)^2-sin(x)^2"},
+		{"cosh(2x)", "cosh(x)^2+sinh(x)^2"},
+		{"e^(x+y)", "e^x*e^y"},
+		{"exp(x-y)", "e^x/e^y"},
+		{"sin(x+y)", "sin(x)*cos(y)+cos(x)*sin(y)"},
+		{"cos(x-y)", "cos(x)*cos(y)+sin(x)*sin(y)"},
+		{"sinh(x-y)", "sinh(x)*cosh(y)-cosh(x)*sinh(y)"},
+		{"tan(x)", "sin(x)/cos(x)"},
+		{"tanh(x)", "sinh(x)/cosh(x)"},
+		{"sin(x)^2+cos(x)^2", "sin(x)^2+cos(x)^2"},
+		{"x > 0 ? ln(x*y) : 0", "piecewise(x>0,log(x)+log(y),0)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Simplify(ExpandIdentities)
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestSimplifyContract(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"sin(x)^2+cos(x)^2", "1"},
+		{"cos(x)^2+sin(x)^2", "1"},
+		{"3+sin(x)^2+y+cos(x)^2", "4+y"},
+		{"cosh(x)^2-sinh(x)^2", "1"},
+		{"cos(x)^2-sin(x)^2", "cos(2*x)"},
+		{"ln(x)+ln(y)", "log(x*y)"},
+		{"ln(x)-ln(y)", "log(x/y)"},
+		{"2*ln(x)", "log(x^2)"},
+		{"2*sin(x)*cos(x)", "sin(2*x)"},
+		{"exp(x)*exp(y)", "e^(x+y)"},
+		{"e^x*y*e^y", "e^(x+y)*y"},
+		{"exp(x)/exp(y)", "e^(x-y)"},
+		{"sin(x)*cos(y)+cos(x)*sin(y)", "sin(x+y)"},
+		{"cos(x)*cos(y)-sin(x)*sin(y)", "cos(x+y)"},
+		{"sin(x)/cos(x)", "tan(x)"},
+		{"x > 0 ? sin(x)^2+cos(x)^2 : 0", "piecewise(x>0,1,0)"},
+		{"sinh(x)/cosh(x)", "tanh(x)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Simplify(ContractIdentities)
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestSimplifyRoundTrip(t *testing.T) {
+	tests := []string{
+		"sin(x+y)",
+		"cos(x+y)",
+		"log(x*y)",
+		"e^(x+y)",
+		"sin(2*x)",
+		"tan(x)",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			f, errs := Parse(test)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Simplify(ExpandIdentities)
+			f.Simplify(ContractIdentities)
+			if f.String() != test {
+				t.Fatal(f, "!=", test)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package formulae
+
+import (
+	"fmt"
+
+	"github.com/tdewolff/formulae/hash"
+)
+
+// maxIntegralDepth limits the recursion of integration by parts.
+const maxIntegralDepth = 8
+
+var errNoIntegral = fmt.Errorf("no integral")
+
+// Integral returns the antiderivative of f with respect to x, omitting the constant of integration. It handles polynomials, the elementary functions, linearity, substitution of chain-rule patterns and integration by parts of common products, and returns an error for integrands it cannot solve.
+func (f *Function) Integral() (*Function, error) {
+	root, err := integrate(f.root, "x", 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot integrate '%v'", f.root)
+	}
+	root = Optimize(root)
+
+	g := &Function{
+		root:          root,
+		Vars:          f.Vars,
+		nthDerivative: f.nthDerivative - 1,
+		params:        f.params,
+	}
+	if 0 < f.nthDerivative {
+		if f.wrt[len(f.wrt)-1] != "x" {
+			g.nthDerivative = 0 // cannot be expressed as a sequence of derivatives to x
+		} else {
+			g.wrt = append([]string{}, f.wrt[:len(f.wrt)-1]...)
+		}
+	}
+	return g, nil
+}
+
+func integrate(in Node, x string, depth int) (Node, error) {
+	if maxIntegralDepth < depth {
+		return nil, errNoIntegral
+	} else if !dependsOn(in, x) {
+		return &Expr{op: MultiplyOp, l: in, r: &Variable{name: x, pos: -1}}, nil // c*x
+	}
+
+	switch n := in.(type) {
+	case *UnaryExpr:
+		if n.op != MinusOp {
+			return nil, errNoIntegral
+		}
+		a, err := integrate(n.a, x, depth)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{op: n.op, a: a}, nil
+	case *Expr:
+		if n.op == AddOp || n.op == SubtractOp {
+			l, err := integrate(n.l, x, depth)
+			if err != nil {
+				return nil, err
+			}
+			r, err := integrate(n.r, x, depth)
+			if err != nil {
+				return nil, err
+			}
+			return &Expr{op: n.op, l: l, r: r}, nil
+		}
+	}
+
+	p := newProduct(in, x)
+	if y := p.substitute(x); y != nil {
+		return y, nil
+	} else if y := p.expTrig(x); y != nil {
+		return y, nil
+	} else if y, err := p.byParts(x, depth); err == nil {
+		return y, nil
+	}
+
+	// distribute the product over a sum
+	for i, factor := range p.factors {
+		if sum, ok := factor.(*Expr); ok && (sum.op == AddOp || sum.op == SubtractOp) {
+			rest := p.without(i)
+			l, err := integrate(Optimize(&Expr{op: MultiplyOp, l: rest, r: sum.l}), x, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			r, err := integrate(Optimize(&Expr{op: MultiplyOp, l: rest, r: sum.r}), x, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			return &Expr{op: sum.op, l: l, r: r}, nil
+		}
+	}
+	return nil, errNoIntegral
+}
+
+// dependsOn returns true if the variable name occurs in n.
+func dependsOn(in Node, name string) bool {
+	switch n := in.(type) {
+	case *Variable:
+		return n.name == name
+	case *Expr:
+		return dependsOn(n.l, name) || dependsOn(n.r, name)
+	case *UnaryExpr:
+		return dependsOn(n.a, name)
+	case *Func:
+		for _, arg := range n.args {
+			if dependsOn(arg, name) {
+				return true
+			}
+		}
+	case *Piecewise:
+		for _, arg := range n.args {
+			if dependsOn(arg, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// substitute replaces all occurrences of the variable name in n by expr.
+func substitute(in Node, name string, expr Node) Node {
+	switch n := in.(type) {
+	case *Variable:
+		if n.name == name {
+			return expr
+		}
+	case *Expr:
+		return &Expr{op: n.op, l: substitute(n.l, name, expr), r: substitute(n.r, name, expr)}
+	case *UnaryExpr:
+		return &UnaryExpr{op: n.op, a: substitute(n.a, name, expr)}
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = substitute(arg, name, expr)
+		}
+		return &Func{name: n.name, args: args}
+	case *Piecewise:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = substitute(arg, name, expr)
+		}
+		return &Piecewise{args: args}
+	}
+	return in
+}
+
+////////////////
+
+// product is an integrand written as a constant times a product of factors that depend on x. Factors with an equal base are merged by adding their numeric exponents.
+type product struct {
+	num, den []Node // constant factors of numerator and denominator
+	factors  []Node
+}
+
+func newProduct(n Node, x string) *product {
+	p := &product{}
+	var bases []Node
+	var exps []complex128
+	var add func(Node, bool)
+	add = func(in Node, inverse bool) {
+		if !dependsOn(in, x) {
+			if inverse {
+				p.den = append(p.den, in)
+			} else {
+				p.num = append(p.num, in)
+			}
+			return
+		}
+
+		k := complex(1.0, 0.0)
+		switch n := in.(type) {
+		case *UnaryExpr:
+			if n.op == MinusOp {
+				add(MinusOneNode, false)
+				add(n.a, inverse)
+				return
+			}
+		case *Expr:
+			if n.op == MultiplyOp {
+				add(n.l, inverse)
+				add(n.r, inverse)
+				return
+			} else if n.op == DivideOp {
+				add(n.l, inverse)
+				add(n.r, !inverse)
+				return
+			} else if rNumber, ok := n.r.(*Number); ok && n.op == PowerOp {
+				in, k = n.l, rNumber.val
+			}
+		}
+		if inverse {
+			k = -k
+		}
+		for i, base := range bases {
+			if base.Equal(in) {
+				exps[i] += k
+				return
+			}
+		}
+		bases = append(bases, in)
+		exps = append(exps, k)
+	}
+	add(n, false)
+
+	for i, base := range bases {
+		if exps[i] == 1.0 {
+			p.factors = append(p.factors, base)
+		} else if exps[i] != 0.0 {
+			p.factors = append(p.factors, &Expr{op: PowerOp, l: base, r: &Number{val: exps[i]}})
+		}
+	}
+	return p
+}
+
+// constant returns the constant factor of the product.
+func (p *product) constant() Node {
+	return &Expr{op: DivideOp, l: multiplyNodes(p.num), r: multiplyNodes(p.den)}
+}
+
+// without returns the product without the i-th factor.
+func (p *product) without(i int) Node {
+	factors := append(append([]Node{}, p.factors[:i]...), p.factors[i+1:]...)
+	return &Expr{op: MultiplyOp, l: p.constant(), r: multiplyNodes(factors)}
+}
+
+// ratio returns the constant c such that p equals c*q, or nil if p and q differ by more than a constant.
+func (p *product) ratio(q *product) Node {
+	if len(p.factors) != len(q.factors) {
+		return nil
+	}
+	used := make([]bool, len(q.factors))
+	for _, factor := range p.factors {
+		found := false
+		for j, qFactor := range q.factors {
+			if !used[j] && factor.Equal(qFactor) {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return &Expr{op: DivideOp, l: p.constant(), r: q.constant()}
+}
+
+// substitute integrates a factor g(u) whose inner derivative du/dx equals the remaining factors up to a constant, so that the integral is c*G(u).
+func (p *product) substitute(x string) Node {
+	for i, factor := range p.factors {
+		rest := &product{num: p.num, den: p.den}
+		rest.factors = append(append([]Node{}, p.factors[:i]...), p.factors[i+1:]...)
+
+		for _, sub := range outerIntegrals(factor, x) {
+			du := Optimize(sub.u.Derivative(x))
+			if du.Equal(ZeroNode) {
+				continue
+			} else if c := rest.ratio(newProduct(du, x)); c != nil {
+				return &Expr{op: MultiplyOp, l: c, r: sub.G}
+			}
+		}
+	}
+	return nil
+}
+
+// expTrig integrates products of an exponential and a sine or cosine with linear arguments.
+func (p *product) expTrig(x string) Node {
+	if len(p.factors) != 2 {
+		return nil
+	}
+
+	var base, u, v Node
+	var trig *Func
+	for _, factor := range p.factors {
+		if nFunc, ok := factor.(*Func); ok && (nFunc.name == hash.Sin || nFunc.name == hash.Cos) {
+			trig = nFunc
+			v = nFunc.args[0]
+		} else if nExpr, ok := factor.(*Expr); ok && nExpr.op == PowerOp && !dependsOn(nExpr.l, x) {
+			base = nExpr.l
+			u = nExpr.r
+		}
+	}
+	if trig == nil || base == nil {
+		return nil
+	}
+	a := Optimize(u.Derivative(x))
+	b := Optimize(v.Derivative(x))
+	if dependsOn(a, x) || dependsOn(b, x) {
+		return nil
+	}
+	if bVariable, ok := base.(*Variable); !ok || bVariable.name != "e" {
+		a = &Expr{op: MultiplyOp, l: a, r: &Func{name: hash.Log, args: []Node{base}}}
+	}
+
+	// int c^u sin(v) dx = c^u (a sin(v) - b cos(v)) / (a^2+b^2)
+	// int c^u cos(v) dx = c^u (a cos(v) + b sin(v)) / (a^2+b^2)
+	sin := &Func{name: hash.Sin, args: []Node{v}}
+	cos := &Func{name: hash.Cos, args: []Node{v}}
+	var y Node
+	if trig.name == hash.Sin {
+		y = &Expr{
+			op: SubtractOp,
+			l:  &Expr{op: MultiplyOp, l: a, r: sin},
+			r:  &Expr{op: MultiplyOp, l: b, r: cos},
+		}
+	} else {
+		y = &Expr{
+			op: AddOp,
+			l:  &Expr{op: MultiplyOp, l: a, r: cos},
+			r:  &Expr{op: MultiplyOp, l: b, r: sin},
+		}
+	}
+	return &Expr{
+		op: MultiplyOp,
+		l:  p.constant(),
+		r: &Expr{
+			op: DivideOp,
+			l:  &Expr{op: MultiplyOp, l: &Expr{op: PowerOp, l: base, r: u}, r: y},
+			r: &Expr{
+				op: AddOp,
+				l:  &Expr{op: PowerOp, l: a, r: TwoNode},
+				r:  &Expr{op: PowerOp, l: b, r: TwoNode},
+			},
+		},
+	}
+}
+
+// byParts integrates a product using int u dv = u v - int v du, where u is chosen as the factor that comes first in the order logarithmic, inverse trigonometric, algebraic, trigonometric, exponential.
+func (p *product) byParts(x string, depth int) (Node, error) {
+	if len(p.factors) < 2 {
+		return nil, errNoIntegral
+	}
+
+	best, bestRank := -1, 5
+	for i, factor := range p.factors {
+		if rank := partsRank(factor, x); rank < bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	if best == -1 {
+		return nil, errNoIntegral
+	}
+
+	u := p.factors[best]
+	dv := p.without(best)
+	v, err := integrate(Optimize(dv), x, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	v = Optimize(v)
+	vdu, err := integrate(Optimize(&Expr{op: MultiplyOp, l: v, r: u.Derivative(x)}), x, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{
+		op: SubtractOp,
+		l:  &Expr{op: MultiplyOp, l: u, r: v},
+		r:  vdu,
+	}, nil
+}
+
+// partsRank returns the order of preference for choosing u in integration by parts, or 5 if n is not suitable.
+func partsRank(in Node, x string) int {
+	switch n := in.(type) {
+	case *Func:
+		switch n.name {
+		case hash.Log, hash.Log10, hash.Log2:
+			return 0
+		case hash.Arcsin, hash.Arccos, hash.Arctan, hash.Arcsinh, hash.Arccosh, hash.Arctanh:
+			return 1
+		}
+	case *Variable:
+		return 2
+	case *Expr:
+		if rNumber, ok := n.r.(*Number); ok && n.op == PowerOp && imag(rNumber.val) == 0.0 && 0.0 < real(rNumber.val) {
+			if _, ok := n.l.(*Variable); ok && real(rNumber.val) == float64(int(real(rNumber.val))) {
+				return 2
+			}
+		}
+	}
+	return 5
+}
+
+////////////////
+
+// outerIntegral is the integral G(u) of the outer function of a factor g(u) with respect to u.
+type outerIntegral struct {
+	u Node
+	G Node
+}
+
+// outerIntegrals returns the ways to write the factor n as g(u) with a known integral G(u).
+func outerIntegrals(in Node, x string) []outerIntegral {
+	subs := []outerIntegral{}
+	switch n := in.(type) {
+	case *Func:
+		if len(n.args) == 1 {
+			if G := funcIntegral(n.name, n.args[0]); G != nil {
+				subs = append(subs, outerIntegral{n.args[0], G})
+			}
+		}
+	case *Expr:
+		if n.op == PowerOp && !dependsOn(n.r, x) {
+			subs = append(subs, outerIntegral{n.l, powerIntegral(n.l, n.r)})
+		} else if n.op == PowerOp && !dependsOn(n.l, x) {
+			if lVariable, ok := n.l.(*Variable); ok && lVariable.name == "e" {
+				subs = append(subs, outerIntegral{n.r, n}) // e^u
+			} else {
+				subs = append(subs, outerIntegral{n.r, &Expr{ // c^u/log(c)
+					op: DivideOp,
+					l:  n,
+					r:  &Func{name: hash.Log, args: []Node{n.l}},
+				}})
+			}
+		}
+	}
+	return append(subs, outerIntegral{in, powerIntegral(in, OneNode)}) // u^2/2
+}
+
+// powerIntegral returns the integral of u^k with respect to u.
+func powerIntegral(u, k Node) Node {
+	if k.Equal(MinusOneNode) {
+		return &Func{name: hash.Log, args: []Node{u}}
+	}
+	k1 := Optimize(&Expr{op: AddOp, l: k, r: OneNode})
+	return &Expr{
+		op: DivideOp,
+		l:  &Expr{op: PowerOp, l: u, r: k1},
+		r:  k1,
+	}
+}
+
+// funcIntegral returns the integral of the function name of u with respect to u, or nil if unknown.
+func funcIntegral(name hash.Hash, u Node) Node {
+	f := func(name hash.Hash, a Node) Node {
+		return &Func{name: name, args: []Node{a}}
+	}
+	mul := func(l, r Node) Node {
+		return &Expr{op: MultiplyOp, l: l, r: r}
+	}
+	sq := &Expr{op: PowerOp, l: u, r: TwoNode}
+	switch name {
+	case hash.Sin:
+		return &UnaryExpr{op: MinusOp, a: f(hash.Cos, u)}
+	case hash.Cos:
+		return f(hash.Sin, u)
+	case hash.Tan:
+		return &UnaryExpr{op: MinusOp, a: f(hash.Log, f(hash.Cos, u))}
+	case hash.Sinh:
+		return f(hash.Cosh, u)
+	case hash.Cosh:
+		return f(hash.Sinh, u)
+	case hash.Tanh:
+		return f(hash.Log, f(hash.Cosh, u))
+	case hash.Sqrt:
+		return &Expr{ // 2/3*u^(3/2)
+			op: MultiplyOp,
+			l:  &Number{val: 2.0 / 3.0},
+			r:  &Expr{op: PowerOp, l: u, r: &Number{val: 1.5}},
+		}
+	case hash.Cbrt:
+		return &Expr{ // 3/4*u*cbrt(u)
+			op: MultiplyOp,
+			l:  &Number{val: 0.75},
+			r:  mul(u, f(hash.Cbrt, u)),
+		}
+	case hash.Log, hash.Log10, hash.Log2:
+		y := &Expr{op: SubtractOp, l: mul(u, f(hash.Log, u)), r: u} // u*log(u)-u
+		if name == hash.Log10 {
+			return &Expr{op: DivideOp, l: y, r: f(hash.Log, &Number{val: 10})}
+		} else if name == hash.Log2 {
+			return &Expr{op: DivideOp, l: y, r: f(hash.Log, TwoNode)}
+		}
+		return y
+	case hash.Arcsin: // u*arcsin(u) + sqrt(1-u^2)
+		return &Expr{op: AddOp, l: mul(u, f(name, u)), r: f(hash.Sqrt, &Expr{op: SubtractOp, l: OneNode, r: sq})}
+	case hash.Arccos: // u*arccos(u) - sqrt(1-u^2)
+		return &Expr{op: SubtractOp, l: mul(u, f(name, u)), r: f(hash.Sqrt, &Expr{op: SubtractOp, l: OneNode, r: sq})}
+	case hash.Arctan: // u*arctan(u) - log(1+u^2)/2
+		return &Expr{op: SubtractOp, l: mul(u, f(name, u)), r: &Expr{op: DivideOp, l: f(hash.Log, &Expr{op: AddOp, l: OneNode, r: sq}), r: TwoNode}}
+	case hash.Arcsinh: // u*arcsinh(u) - sqrt(u^2+1)
+		return &Expr{op: SubtractOp, l: mul(u, f(name, u)), r: f(hash.Sqrt, &Expr{op: AddOp, l: sq, r: OneNode})}
+	case hash.Arccosh: // u*arccosh(u) - sqrt(u^2-1)
+		return &Expr{op: SubtractOp, l: mul(u, f(name, u)), r: f(hash.Sqrt, &Expr{op: SubtractOp, l: sq, r: OneNode})}
+	case hash.Arctanh: // u*arctanh(u) + log(1-u^2)/2
+		return &Expr{op: AddOp, l: mul(u, f(name, u)), r: &Expr{op: DivideOp, l: f(hash.Log, &Expr{op: SubtractOp, l: OneNode, r: sq}), r: TwoNode}}
+	case hash.Erf: // u*erf(u) + e^(-u^2)/sqrt(pi)
+		return &Expr{
+			op: AddOp,
+			l:  mul(u, f(name, u)),
+			r: &Expr{
+				op: DivideOp,
+				l:  &Expr{op: PowerOp, l: &Variable{name: "e", pos: -1}, r: &UnaryExpr{op: MinusOp, a: sq}},
+				r:  f(hash.Sqrt, &Variable{name: "pi", pos: -1}),
+			},
+		}
+	}
+	return nil
+}
+
+// multiplyNodes returns the product of all nodes, or one if there are none.
+func multiplyNodes(nodes []Node) Node {
+	if len(nodes) == 0 {
+		return OneNode
+	}
+	y := nodes[0]
+	for _, n := range nodes[1:] {
+		y = &Expr{op: MultiplyOp, l: y, r: n}
+	}
+	return y
+}
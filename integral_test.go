@@ -0,0 +1,90 @@
+package formulae
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestIntegral(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"5", "5*x"},
+		{"x^2", "x^3/3"},
+		{"1/x", "log(x)"},
+		{"e^x", "e^x"},
+		{"2^x", "2^x/log(2)"},
+		{"sin(x)", "-cos(x)"},
+		{"ln(x)", "x*log(x)-x"},
+		{"tan x", "-log(cos(x))"},
+		{"2x*cos(x^2)", "sin(x^2)"},
+		{"sin(x)*cos(x)", "sin(x)^2/2"},
+		{"x/(x^2+1)", "0.5*log(x^2+1)"},
+		{"x*e^x", "x*e^x-e^x"},
+		{"e^x*sin(x)", "e^x*(sin(x)-cos(x))/2"},
+		{"3x^2+2x+1", ""},
+		{"cos(3x+1)", ""},
+		{"x^2*e^x", ""},
+		{"x*sin(x)", ""},
+		{"x*ln(x)", ""},
+		{"arctan(x)", ""},
+		{"e^(2x)*cos(3x)", ""},
+		{"(x+1)*x", ""},
+		{"sin(x)^2*cos(x)", ""},
+		{"erf(x)", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(f, errs)
+			}
+			F, err := f.Integral()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.out != "" && F.String() != test.out {
+				t.Fatal(F.String(), "!=", test.out)
+			}
+
+			// the derivative of the integral must equal the integrand
+			dF := F.Derivative()
+			for _, x := range []complex128{0.3, 1.7, 2.2} {
+				y, err := f.Calc(x)
+				if err != nil {
+					t.Fatal(err)
+				}
+				y2, err := dF.Calc(x)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cmplx.Abs(y-y2) > Epsilon {
+					t.Fatal(x, y2, "!=", y)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegralErr(t *testing.T) {
+	f, _ := Parse("e^(x^2)")
+	if _, err := f.Integral(); err == nil || err.Error() != "cannot integrate 'e^x^2'" {
+		t.Fatal(err, "!=", "cannot integrate 'e^x^2'")
+	}
+}
+
+func TestIntegralLaTeX(t *testing.T) {
+	f, _ := Parse("x^2")
+	F, _ := f.Integral()
+	if latex := F.LaTeX(); latex != "\\int f(x) \\,dx = \\frac{x^{3}}{3}" {
+		t.Fatal(latex, "!=", "\\int f(x) \\,dx = \\frac{x^{3}}{3}")
+	}
+
+	f, _ = Parse("2")
+	F, _ = f.Integral()
+	if latex := F.Derivative().LaTeX(); latex != "f(x) = 2" {
+		t.Fatal(latex, "!=", "f(x) = 2")
+	}
+}
@@ -20,12 +20,28 @@ const (
 	FuncOp
 	OpenOp
 	CloseOp
+	CommaOp
 	AddOp
 	SubtractOp
 	MinusOp
 	MultiplyOp
 	DivideOp
 	PowerOp
+	LessOp
+	LessEqualOp
+	GreaterOp
+	GreaterEqualOp
+	EqualOp
+	NotEqualOp
+	AndOp
+	OrOp
+	NotOp
+	QuestionOp
+	ColonOp
+	OpenAbsOp
+	CloseAbsOp
+	FactorialOp
+	ModOp
 )
 
 func (op Operator) String() string {
@@ -36,6 +52,8 @@ func (op Operator) String() string {
 		return "("
 	case CloseOp:
 		return ")"
+	case CommaOp:
+		return ","
 	case AddOp:
 		return "+"
 	case SubtractOp:
@@ -48,6 +66,34 @@ func (op Operator) String() string {
 		return "/"
 	case PowerOp:
 		return "^"
+	case LessOp:
+		return "<"
+	case LessEqualOp:
+		return "<="
+	case GreaterOp:
+		return ">"
+	case GreaterEqualOp:
+		return ">="
+	case EqualOp:
+		return "=="
+	case NotEqualOp:
+		return "!="
+	case AndOp:
+		return "&&"
+	case OrOp:
+		return "||"
+	case NotOp:
+		return "!"
+	case QuestionOp:
+		return "?"
+	case ColonOp:
+		return ":"
+	case OpenAbsOp, CloseAbsOp:
+		return "|"
+	case FactorialOp:
+		return "!"
+	case ModOp:
+		return "%"
 	}
 	return "Invalid(" + strconv.Itoa(int(op)) + ")"
 }
@@ -94,6 +140,9 @@ type Lexer struct {
 	lastTT   TokenType
 	lastOp   Operator
 	lastFunc hash.Hash
+	absDepth int  // number of absolute value bars that are open
+	afterWS  bool // the last token is whitespace
+	err      error
 }
 
 // NewLexer returns a new Lexer for a given io.Reader.
@@ -105,6 +154,9 @@ func NewLexer(r io.Reader) *Lexer {
 
 // Err returns the error encountered during lexing, this is often io.EOF but also other errors can be returned.
 func (l *Lexer) Err() error {
+	if l.err != nil {
+		return l.err
+	}
 	return l.r.Err()
 }
 
@@ -122,8 +174,8 @@ func (l *Lexer) Next() (TokenType, []byte) {
 	// Add in extra multiplier
 	isNumeric := l.isNumeric()
 	isIdentifier, nIdent := l.isIdentifierStart()
-	if isNumeric || isIdentifier || l.r.Peek(0) == '(' {
-		if l.lastTT == NumericToken || l.lastTT == IdentifierToken || l.lastTT == OperatorToken && l.lastOp == CloseOp {
+	if isNumeric || isIdentifier && !l.isModKeyword() || l.r.Peek(0) == '(' || l.isOpenAbs() {
+		if !l.afterWS && l.afterOperand() {
 			l.lastTT = OperatorToken
 			l.lastOp = MultiplyOp
 			return OperatorToken, []byte("*")
@@ -150,7 +202,10 @@ func (l *Lexer) Next() (TokenType, []byte) {
 		l.r.Move(1)
 		tt = UnknownToken
 	}
-	l.lastTT = tt
+	l.afterWS = tt == WhitespaceToken
+	if !l.afterWS {
+		l.lastTT = tt
+	}
 	return tt, l.r.Shift()
 }
 
@@ -185,6 +240,12 @@ func (l *Lexer) isIdentifierStart() (bool, int) {
 	if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
 		return true, 1
 	}
+	if c == '?' && !l.afterOperand() {
+		// pattern variable, such as ?a
+		if c = l.r.Peek(1); (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			return true, 2
+		}
+	}
 	if c >= 0xC0 {
 		if r, n := l.r.PeekRune(0); unicode.IsOneOf(identifierStart, r) {
 			return true, n
@@ -193,6 +254,57 @@ func (l *Lexer) isIdentifierStart() (bool, int) {
 	return false, 0
 }
 
+// isModKeyword returns true if the next identifier is mod, which is the modulo operator when it follows an operand.
+func (l *Lexer) isModKeyword() bool {
+	if l.r.Peek(0)|0x20 != 'm' || l.r.Peek(1)|0x20 != 'o' || l.r.Peek(2)|0x20 != 'd' {
+		return false
+	}
+	l.r.Move(3)
+	isIdent, _ := l.isIdentifierContinue()
+	l.r.Move(-3)
+	return !isIdent
+}
+
+// afterOperand returns true if the last token ends an operand, such as a number, an identifier, a closing parenthesis or bar, or a factorial.
+func (l *Lexer) afterOperand() bool {
+	return l.lastTT == NumericToken || l.lastTT == IdentifierToken || l.lastTT == OperatorToken && (l.lastOp == CloseOp || l.lastOp == CloseAbsOp || l.lastOp == FactorialOp)
+}
+
+// isOpenAbs returns true if the next bar opens an absolute value. A bar after an operand opens a new absolute value after an implicit multiplication if none is open, as in 2|x|, or if it is followed by an operand and enough bars remain to close it, as in |2|x||. Otherwise it closes the innermost absolute value that is open. Two bars after an operand with no open absolute value are a logical or.
+func (l *Lexer) isOpenAbs() bool {
+	if l.r.Peek(0) != '|' {
+		return false
+	} else if !l.afterOperand() {
+		return true
+	} else if l.absDepth == 0 {
+		return l.r.Peek(1) != '|'
+	} else if !l.isOperandStart(l.skipSpaces(1)) {
+		return false
+	}
+
+	bars := 0
+	for i := 1; l.r.Peek(i) != 0; i++ {
+		if l.r.Peek(i) == '|' {
+			bars++
+		}
+	}
+	return l.absDepth+1 <= bars
+}
+
+// skipSpaces returns the offset of the first character at or after offset i that is not a space or tab.
+func (l *Lexer) skipSpaces(i int) int {
+	for c := l.r.Peek(i); c == ' ' || c == '\t'; c = l.r.Peek(i) {
+		i++
+	}
+	return i
+}
+
+// isOperandStart returns true if the character at offset i starts an operand, such as a number, an identifier or an opening parenthesis.
+func (l *Lexer) isOperandStart(i int) bool {
+	c := l.r.Peek(i)
+	return c >= '0' && c <= '9' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '(' || c >= 0xC0
+}
+
 func (l *Lexer) isIdentifierContinue() (bool, int) {
 	c := l.r.Peek(0)
 	if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
@@ -232,7 +344,10 @@ func (l *Lexer) consumeIdentifierToken() TokenType {
 
 	ident := parse.ToLower(l.r.Lexeme())
 	h := hash.ToHash(ident)
-	if h != 0 {
+	if h == hash.Mod && l.afterOperand() {
+		l.lastOp = ModOp
+		return OperatorToken
+	} else if h != 0 {
 		l.lastOp = FuncOp
 		l.lastFunc = h
 		return OperatorToken
@@ -242,16 +357,19 @@ func (l *Lexer) consumeIdentifierToken() TokenType {
 
 func (l *Lexer) consumeOperatorToken() bool {
 	op := UnknownOp
+	n := 1
 	c := l.r.Peek(0)
 	switch c {
 	case '(':
 		op = OpenOp
 	case ')':
 		op = CloseOp
+	case ',':
+		op = CommaOp
 	case '+':
 		op = AddOp
 	case '-':
-		if l.lastTT == ErrorToken || l.lastTT == OperatorToken && l.lastOp != CloseOp {
+		if !l.afterOperand() {
 			op = MinusOp
 		} else {
 			op = SubtractOp
@@ -262,13 +380,60 @@ func (l *Lexer) consumeOperatorToken() bool {
 		op = DivideOp
 	case '^':
 		op = PowerOp
+	case '%':
+		op = ModOp
+	case '<':
+		op = LessOp
+		if l.r.Peek(1) == '=' {
+			op, n = LessEqualOp, 2
+		}
+	case '>':
+		op = GreaterOp
+		if l.r.Peek(1) == '=' {
+			op, n = GreaterEqualOp, 2
+		}
+	case '=':
+		if l.r.Peek(1) == '=' {
+			op, n = EqualOp, 2
+		}
+	case '!':
+		if l.r.Peek(1) == '=' && l.afterOperand() {
+			op, n = NotEqualOp, 2
+		} else if !l.afterOperand() {
+			op = NotOp
+		} else {
+			op = FactorialOp
+		}
+	case '&':
+		if l.r.Peek(1) == '&' {
+			op, n = AndOp, 2
+		}
+	case '|':
+		if l.isOpenAbs() {
+			op = OpenAbsOp
+			l.absDepth++
+		} else if 0 < l.absDepth {
+			if l.r.Peek(1) == '|' && l.isOperandStart(l.skipSpaces(2)) {
+				// either two bars that close and open, or a logical or, as in |a||b|
+				l.err = ParseErrorf(l.Pos(), "ambiguous absolute value bars")
+				return false
+			}
+			op = CloseAbsOp
+			l.absDepth--
+		} else if l.r.Peek(1) == '|' {
+			op, n = OrOp, 2
+		}
+	case '?':
+		op = QuestionOp
+	case ':':
+		op = ColonOp
 	}
 
 	if op == UnknownOp {
 		return false
 	}
 
-	l.r.Move(1)
+	l.r.Move(n)
 	l.lastOp = op
 	return true
 }
@@ -51,3 +51,58 @@ func TestNumeric(t *testing.T) {
 		})
 	}
 }
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		in  string
+		ops []Operator
+	}{
+		{"x<y", []Operator{LessOp}},
+		{"x<=y", []Operator{LessEqualOp}},
+		{"x>y", []Operator{GreaterOp}},
+		{"x>=y", []Operator{GreaterEqualOp}},
+		{"x==y", []Operator{EqualOp}},
+		{"x!=y", []Operator{NotEqualOp}},
+		{"x&&y||z", []Operator{AndOp, OrOp}},
+		{"!x", []Operator{NotOp}},
+		{"x?y:z", []Operator{QuestionOp, ColonOp}},
+		{"?a", nil},
+		{"|x|", []Operator{OpenAbsOp, CloseAbsOp}},
+		{"||x|-|y||", []Operator{OpenAbsOp, OpenAbsOp, CloseAbsOp, SubtractOp, OpenAbsOp, CloseAbsOp, CloseAbsOp}},
+		{"2|x|", []Operator{MultiplyOp, OpenAbsOp, CloseAbsOp}},
+		{"|x|*|y|", []Operator{OpenAbsOp, CloseAbsOp, MultiplyOp, OpenAbsOp, CloseAbsOp}},
+		{"|2|x||", []Operator{OpenAbsOp, MultiplyOp, OpenAbsOp, CloseAbsOp, CloseAbsOp}},
+		{"|x|y", []Operator{OpenAbsOp, CloseAbsOp, MultiplyOp}},
+		{"|x| || |y|", []Operator{OpenAbsOp, CloseAbsOp, OrOp, OpenAbsOp, CloseAbsOp}},
+		{"x!", []Operator{FactorialOp}},
+		{"x!y", []Operator{FactorialOp, MultiplyOp}},
+		{"x!-1", []Operator{FactorialOp, SubtractOp}},
+		{"x%y", []Operator{ModOp}},
+		{"x mod y", []Operator{ModOp}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			ops := []Operator{}
+			l := NewLexer(strings.NewReader(test.in))
+			for {
+				tt, _ := l.Next()
+				if tt == ErrorToken {
+					break
+				} else if tt == UnknownToken {
+					t.Fatal("unknown token")
+				} else if tt == OperatorToken {
+					ops = append(ops, l.Operator())
+				}
+			}
+			if len(ops) != len(test.ops) {
+				t.Fatal(ops, "!=", test.ops)
+			}
+			for i := range ops {
+				if ops[i] != test.ops[i] {
+					t.Fatal(ops, "!=", test.ops)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/cmplx"
+	"strings"
 
 	"github.com/tdewolff/formulae/hash"
 )
@@ -12,8 +13,28 @@ type Node interface {
 	String() string
 	LaTeX() string
 	Equal(Node) bool
-	Derivative() Node
-	Calc(complex128, Vars) (complex128, error)
+	Derivative(string) Node
+	Calc(Vars) (complex128, error)
+	CalcReal(Vars) (float64, error)
+}
+
+// DomainError is returned when an argument lies outside the domain of a function or operator, such as at a pole of the gamma function, or by real evaluation when the result would leave the real line.
+type DomainError struct {
+	Op  string // function or operator, such as sqrt or ^, or empty for a variable or number that is not real
+	Msg string
+}
+
+func (e DomainError) Error() string {
+	return e.Msg
+}
+
+func domainErrorf(op, format string, args ...interface{}) DomainError {
+	return DomainError{Op: op, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isPole returns true if z is a non-positive integer, where the gamma and polygamma functions have their poles.
+func isPole(z complex128) bool {
+	return imag(z) == 0.0 && real(z) <= 0.0 && real(z) == math.Trunc(real(z))
 }
 
 var ZeroNode = &Number{val: 0 + 0i}
@@ -39,11 +60,14 @@ func negateNode(n Node) Node {
 	return &UnaryExpr{op: MinusOp, a: n}
 }
 
+// Optimize returns a simplified expression of in, such as by folding numbers and removing additions of zero and multiplications by one. It does not modify in, but the result may share unchanged subexpressions with in.
 func Optimize(in Node) Node {
 	switch n := in.(type) {
 	case *Expr:
-		n.l = Optimize(n.l)
-		n.r = Optimize(n.r)
+		if l, r := Optimize(n.l), Optimize(n.r); l != n.l || r != n.r {
+			n = &Expr{op: n.op, l: l, r: r}
+			in = n
+		}
 		switch n.op {
 		case AddOp:
 			if n.l.Equal(ZeroNode) {
@@ -94,7 +118,9 @@ func Optimize(in Node) Node {
 				if lNumber != nil && rNumber != nil {
 					return &Number{val: lNumber.val * rNumber.val}
 				} else if rNumber != nil {
-					n.l, n.r = n.r, n.l
+					n = &Expr{op: n.op, l: n.r, r: n.l}
+					in = n
+					lNumber, rNumber = rNumber, nil
 				}
 
 				// move unary minus outwards
@@ -126,7 +152,7 @@ func Optimize(in Node) Node {
 			} else {
 				lNumber, _ := n.l.(*Number)
 				rNumber, _ := n.r.(*Number)
-				if lNumber != nil && rNumber != nil {
+				if lNumber != nil && rNumber != nil && rNumber.val != 0.0 {
 					return &Number{val: lNumber.val / rNumber.val}
 				}
 
@@ -151,6 +177,14 @@ func Optimize(in Node) Node {
 					})
 				}
 			}
+		case ModOp, LessOp, LessEqualOp, GreaterOp, GreaterEqualOp, EqualOp, NotEqualOp, AndOp, OrOp:
+			lNumber, _ := n.l.(*Number)
+			rNumber, _ := n.r.(*Number)
+			if lNumber != nil && rNumber != nil {
+				if y, err := calcOp(n.op, lNumber.val, rNumber.val); err == nil {
+					return &Number{val: y}
+				}
+			}
 		case PowerOp:
 			if n.l.Equal(ZeroNode) {
 				return ZeroNode
@@ -175,7 +209,11 @@ func Optimize(in Node) Node {
 					return &Number{val: cmplx.Pow(lNumber.val, rNumber.val)}
 				} else if lNumber != nil && lNumber.val == 10+0i {
 					if rFunc, ok := n.r.(*Func); ok && rFunc.name == hash.Log10 {
-						return rFunc.a
+						return rFunc.args[0]
+					}
+				} else if lNumber != nil && lNumber.val == 2+0i {
+					if rFunc, ok := n.r.(*Func); ok && rFunc.name == hash.Log2 {
+						return rFunc.args[0]
 					}
 				}
 
@@ -196,44 +234,103 @@ func Optimize(in Node) Node {
 				}
 
 				if lVariable, ok := n.l.(*Variable); ok && lVariable.name == "e" {
-					if rFunc, ok := n.r.(*Func); ok && rFunc.name == hash.Log {
-						return rFunc.a
+					if rFunc, ok := n.r.(*Func); ok && rFunc.name == hash.Log && len(rFunc.args) == 1 {
+						return rFunc.args[0]
 					}
 				}
 			}
 		}
 	case *UnaryExpr:
-		n.a = Optimize(n.a)
-		if n.op == MinusOp {
-			if aNumber, ok := n.a.(*Number); ok {
-				return &Number{val: -aNumber.val}
-			} else if aUnaryExpr, ok := n.a.(*UnaryExpr); ok && aUnaryExpr.op == MinusOp {
-				return aUnaryExpr.a
+		if a := Optimize(n.a); a != n.a {
+			n = &UnaryExpr{op: n.op, a: a}
+			in = n
+		}
+		if aNumber, ok := n.a.(*Number); ok && (n.op != FactorialOp || isFactorialExact(aNumber.val)) {
+			if y, err := calcUnary(n.op, aNumber.val); err == nil {
+				return &Number{val: y}
 			}
+		} else if aUnaryExpr, ok := n.a.(*UnaryExpr); ok && n.op == MinusOp && aUnaryExpr.op == MinusOp {
+			return aUnaryExpr.a
 		}
+	case *Piecewise:
+		return n.optimize()
 	case *Func:
-		n.a = Optimize(n.a)
+		isReal := true
+		args := make([]Node, len(n.args))
+		changed := false
+		for i, arg := range n.args {
+			args[i] = Optimize(arg)
+			changed = changed || args[i] != arg
+			if aNumber, ok := args[i].(*Number); !ok || imag(aNumber.val) != 0.0 {
+				isReal = false
+			}
+		}
+		if changed {
+			n = &Func{name: n.name, args: args}
+			in = n
+		}
+		if isReal && (n.name == hash.Max || n.name == hash.Min) {
+			y, _ := n.Calc(nil)
+			return &Number{val: y}
+		} else if n.name == hash.Polygamma && n.args[0].Equal(ZeroNode) {
+			return &Func{name: hash.Digamma, args: n.args[1:]}
+		} else if len(n.args) != 1 {
+			break
+		}
+		a := n.args[0]
 		switch n.name {
 		case hash.Log:
-			if aVariable, ok := n.a.(*Variable); ok && aVariable.name == "e" {
+			if aVariable, ok := a.(*Variable); ok && aVariable.name == "e" {
 				return OneNode
 			}
 		case hash.Log10:
-			if aNumber, ok := n.a.(*Number); ok && aNumber.val == 10+0i {
+			if aNumber, ok := a.(*Number); ok && aNumber.val == 10+0i {
 				return OneNode
 			}
-		case hash.Sin, hash.Tan:
-			if aNumber, ok := n.a.(*Number); ok && aNumber.val == 0+0i {
+		case hash.Log2:
+			// fold exact powers of two
+			if aNumber, ok := a.(*Number); ok && imag(aNumber.val) == 0.0 && 0.0 < real(aNumber.val) {
+				if y := math.Log2(real(aNumber.val)); y == math.Trunc(y) {
+					return &Number{val: complex(y, 0.0)}
+				}
+			}
+		case hash.Cbrt:
+			// fold exact cubes
+			if aNumber, ok := a.(*Number); ok && imag(aNumber.val) == 0.0 {
+				if y := math.Cbrt(real(aNumber.val)); y == math.Trunc(y) {
+					return &Number{val: complex(y, 0.0)}
+				}
+			}
+			if isNegative(a) {
+				return negateNode(&Func{name: hash.Cbrt, args: []Node{negateNode(a)}})
+			}
+		case hash.Gamma:
+			// fold gamma(n) = (n-1)! for positive integers whose factorial is exact, like the factorial operator
+			if aNumber, ok := a.(*Number); ok && isFactorialExact(aNumber.val-1.0) {
+				return &Number{val: factorial(aNumber.val - 1.0)}
+			}
+		case hash.Sin, hash.Tan, hash.Erf:
+			if aNumber, ok := a.(*Number); ok && aNumber.val == 0+0i {
 				return ZeroNode
-			} else if isNegative(n.a) {
-				return negateNode(&Func{name: n.name, a: negateNode(n.a)})
+			} else if isNegative(a) {
+				return negateNode(&Func{name: n.name, args: []Node{negateNode(a)}})
 			}
 		case hash.Cos:
-			if aNumber, ok := n.a.(*Number); ok && aNumber.val == 0+0i {
+			if aNumber, ok := a.(*Number); ok && aNumber.val == 0+0i {
 				return OneNode
-			} else if isNegative(n.a) {
-				return &Func{name: hash.Cos, a: negateNode(n.a)}
+			} else if isNegative(a) {
+				return &Func{name: hash.Cos, args: []Node{negateNode(a)}}
 			}
+		case hash.Abs:
+			if aNumber, ok := a.(*Number); ok && imag(aNumber.val) == 0.0 {
+				return &Number{val: complex(math.Abs(real(aNumber.val)), 0.0)}
+			} else if aFunc, ok := a.(*Func); ok && aFunc.name == hash.Abs {
+				return a
+			} else if isNegative(a) {
+				return &Func{name: hash.Abs, args: []Node{negateNode(a)}}
+			}
+		case hash.Max, hash.Min:
+			return a
 		}
 	}
 	return in
@@ -244,224 +341,491 @@ func nodeIsGroup(n Node) bool {
 	return ok
 }
 
+// children returns the operands of n.
+func children(in Node) []Node {
+	switch n := in.(type) {
+	case *Expr:
+		return []Node{n.l, n.r}
+	case *UnaryExpr:
+		return []Node{n.a}
+	case *Func:
+		return n.args
+	case *Piecewise:
+		return n.args
+	}
+	return nil
+}
+
+// withChildren returns a copy of n with its operands replaced.
+func withChildren(in Node, cs []Node) Node {
+	switch n := in.(type) {
+	case *Expr:
+		return &Expr{op: n.op, l: cs[0], r: cs[1]}
+	case *UnaryExpr:
+		return &UnaryExpr{op: n.op, a: cs[0]}
+	case *Func:
+		return &Func{name: n.name, args: append([]Node{}, cs...)}
+	case *Piecewise:
+		return &Piecewise{args: append([]Node{}, cs...)}
+	}
+	return in
+}
+
+// cloneNode returns a deep copy of n.
+func cloneNode(in Node) Node {
+	switch n := in.(type) {
+	case *Number:
+		return &Number{val: n.val}
+	case *Variable:
+		return &Variable{name: n.name, pos: n.pos}
+	}
+	cs := children(in)
+	args := make([]Node, len(cs))
+	for i, c := range cs {
+		args[i] = cloneNode(c)
+	}
+	return withChildren(in, args)
+}
+
 ////////////////
 
 type Func struct {
 	name hash.Hash
-	a    Node
+	args []Node
+}
+
+// funcArity holds the minimum and maximum number of arguments of each function, where a maximum of -1 denotes a variadic function.
+var funcArity = map[hash.Hash][2]int{
+	hash.Sin:       {1, 1},
+	hash.Cos:       {1, 1},
+	hash.Tan:       {1, 1},
+	hash.Arcsin:    {1, 1},
+	hash.Arccos:    {1, 1},
+	hash.Arctan:    {1, 1},
+	hash.Atan2:     {2, 2},
+	hash.Sinh:      {1, 1},
+	hash.Cosh:      {1, 1},
+	hash.Tanh:      {1, 1},
+	hash.Arcsinh:   {1, 1},
+	hash.Arccosh:   {1, 1},
+	hash.Arctanh:   {1, 1},
+	hash.Sqrt:      {1, 1},
+	hash.Cbrt:      {1, 1},
+	hash.Exp:       {1, 1},
+	hash.Pow:       {2, 2},
+	hash.Log:       {1, 2},
+	hash.Log10:     {1, 1},
+	hash.Log2:      {1, 1},
+	hash.Erf:       {1, 1},
+	hash.Gamma:     {1, 1},
+	hash.Digamma:   {1, 1},
+	hash.Polygamma: {2, 2},
+	hash.Max:       {1, -1},
+	hash.Min:       {1, -1},
+	hash.Piecewise: {2, -1},
+	hash.Abs:       {1, 1},
+	hash.Mod:       {2, 2},
+}
+
+func checkArity(name hash.Hash, n int) error {
+	arity, ok := funcArity[name]
+	if !ok {
+		return fmt.Errorf("unknown function '%s'", name)
+	} else if arity[0] == arity[1] && n != arity[0] {
+		if arity[0] == 1 {
+			return fmt.Errorf("function '%s' takes 1 argument, got %d", name, n)
+		}
+		return fmt.Errorf("function '%s' takes %d arguments, got %d", name, arity[0], n)
+	} else if n < arity[0] {
+		return fmt.Errorf("function '%s' takes at least %d arguments, got %d", name, arity[0], n)
+	} else if arity[1] != -1 && arity[1] < n {
+		return fmt.Errorf("function '%s' takes at most %d arguments, got %d", name, arity[1], n)
+	}
+	return nil
+}
+
+// newFuncNode returns the node of the function name applied to args, where ln is the same as log, exp and pow return a power expression, mod returns a modulo expression, and piecewise returns a Piecewise node.
+func newFuncNode(name hash.Hash, args []Node) (Node, error) {
+	if name == hash.Ln {
+		name = hash.Log
+	}
+	if err := checkArity(name, len(args)); err != nil {
+		return nil, err
+	}
+	switch name {
+	case hash.Exp:
+		return &Expr{op: PowerOp, l: &Variable{name: "e", pos: -1}, r: args[0]}, nil
+	case hash.Pow:
+		return &Expr{op: PowerOp, l: args[0], r: args[1]}, nil
+	case hash.Mod:
+		return &Expr{op: ModOp, l: args[0], r: args[1]}, nil
+	case hash.Piecewise:
+		return &Piecewise{args: args}, nil
+	}
+	return &Func{name: name, args: args}, nil
 }
 
 func (n *Func) String() string {
-	return fmt.Sprintf("%v(%v)", n.name, n.a)
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%v(%v)", n.name, strings.Join(args, ","))
 }
 
 func (n *Func) LaTeX() string {
-	name := n.name.String()
+	name := "\\" + n.name.String()
 	if n.name == hash.Log {
-		name = "log"
+		name = "\\log"
+		if len(n.args) == 2 {
+			return fmt.Sprintf("\\log_{%s}\\left(%s\\right)", n.args[0].LaTeX(), n.args[1].LaTeX())
+		}
 	} else if n.name == hash.Log10 {
-		name = "log_{10}"
+		name = "\\log_{10}"
+	} else if n.name == hash.Log2 {
+		name = "\\log_{2}"
 	} else if n.name == hash.Sqrt {
-		return fmt.Sprintf("\\sqrt{%s}", n.a.LaTeX())
+		return fmt.Sprintf("\\sqrt{%s}", n.args[0].LaTeX())
+	} else if n.name == hash.Cbrt {
+		return fmt.Sprintf("\\sqrt[3]{%s}", n.args[0].LaTeX())
+	} else if n.name == hash.Atan2 || n.name == hash.Erf {
+		name = "\\operatorname{" + n.name.String() + "}"
+	} else if n.name == hash.Gamma {
+		name = "\\Gamma"
+	} else if n.name == hash.Digamma {
+		name = "\\psi"
+	} else if n.name == hash.Polygamma {
+		return fmt.Sprintf("\\psi^{(%s)}\\left(%s\\right)", n.args[0].LaTeX(), n.args[1].LaTeX())
+	} else if n.name == hash.Abs {
+		return fmt.Sprintf("\\left|%s\\right|", n.args[0].LaTeX())
 	}
-	_, isVariable := n.a.(*Variable)
-	_, isNumber := n.a.(*Number)
-	if isVariable || isNumber {
-		return fmt.Sprintf("\\%s %s", name, n.a.LaTeX())
+
+	if len(n.args) == 1 {
+		_, isVariable := n.args[0].(*Variable)
+		_, isNumber := n.args[0].(*Number)
+		if isVariable || isNumber {
+			return fmt.Sprintf("%s %s", name, n.args[0].LaTeX())
+		}
+	}
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.LaTeX()
 	}
-	return fmt.Sprintf("\\%s\\left(%s\\right)", name, n.a.LaTeX())
+	return fmt.Sprintf("%s\\left(%s\\right)", name, strings.Join(args, ", "))
 }
 
 func (n *Func) Equal(iother Node) bool {
 	other, ok := iother.(*Func)
-	return ok && n.name == other.name && n.a.Equal(other.a)
+	if !ok || n.name != other.name || len(n.args) != len(other.args) {
+		return false
+	}
+	for i := range n.args {
+		if !n.args[i].Equal(other.args[i]) {
+			return false
+		}
+	}
+	return true
 }
 
-func (n *Func) Derivative() Node {
+func (n *Func) Derivative(x string) Node {
 	switch n.name {
-	case hash.Sin:
-		return &Expr{ // cos(a) * da/dx
-			op: MultiplyOp,
-			l:  &Func{name: hash.Cos, a: n.a},
-			r:  n.a.Derivative(),
+	case hash.Atan2:
+		a, b := n.args[0], n.args[1]
+		return &Expr{ // (b * da/dx - a * db/dx) / (a^2 + b^2)
+			op: DivideOp,
+			l: &Expr{
+				op: SubtractOp,
+				l:  &Expr{op: MultiplyOp, l: b, r: a.Derivative(x)},
+				r:  &Expr{op: MultiplyOp, l: a, r: b.Derivative(x)},
+			},
+			r: &Expr{
+				op: AddOp,
+				l:  &Expr{op: PowerOp, l: b, r: TwoNode},
+				r:  &Expr{op: PowerOp, l: a, r: TwoNode},
+			},
 		}
-	case hash.Cos:
-		return &Expr{ // -sin(a) * da/dx
-			op: MultiplyOp,
-			l:  &UnaryExpr{op: MinusOp, a: &Func{name: hash.Sin, a: n.a}},
-			r:  n.a.Derivative(),
+	case hash.Log:
+		if len(n.args) == 2 {
+			return (&Expr{ // log(a) / log(base)
+				op: DivideOp,
+				l:  &Func{name: hash.Log, args: n.args[1:]},
+				r:  &Func{name: hash.Log, args: n.args[:1]},
+			}).Derivative(x)
 		}
-	case hash.Tan:
-		return &Expr{ // 1/cos(a)^2 * da/dx
+	case hash.Polygamma:
+		return &Expr{ // polygamma(m+1,a) * da/dx
 			op: MultiplyOp,
+			l: &Func{name: hash.Polygamma, args: []Node{
+				&Expr{op: AddOp, l: n.args[0], r: OneNode},
+				n.args[1],
+			}},
+			r: n.args[1].Derivative(x),
+		}
+	case hash.Max, hash.Min:
+		if len(n.args) == 1 {
+			return n.args[0].Derivative(x)
+		}
+		a := n.args[0]
+		b := n.args[1]
+		if 2 < len(n.args) {
+			b = &Func{name: n.name, args: n.args[1:]}
+		}
+
+		// max(a,b) = (a + b + |a-b|) / 2 and min(a,b) = (a + b - |a-b|) / 2, with |a-b| = sqrt((a-b)^2)
+		op := AddOp
+		if n.name == hash.Min {
+			op = SubtractOp
+		}
+		da, db := a.Derivative(x), b.Derivative(x)
+		return &Expr{ // (da/dx + db/dx ± (a-b) * (da/dx - db/dx) / |a-b|) / 2
+			op: DivideOp,
 			l: &Expr{
-				op: DivideOp,
-				l:  OneNode,
+				op: op,
+				l:  &Expr{op: AddOp, l: da, r: db},
 				r: &Expr{
-					op: PowerOp,
-					l:  &Func{name: hash.Cos, a: n.a},
-					r:  TwoNode,
+					op: DivideOp,
+					l: &Expr{
+						op: MultiplyOp,
+						l:  &Expr{op: SubtractOp, l: a, r: b},
+						r:  &Expr{op: SubtractOp, l: da, r: db},
+					},
+					r: &Func{name: hash.Sqrt, args: []Node{&Expr{
+						op: PowerOp,
+						l:  &Expr{op: SubtractOp, l: a, r: b},
+						r:  TwoNode,
+					}}},
 				},
 			},
-			r: n.a.Derivative(),
+			r: TwoNode,
+		}
+	}
+
+	// chain rule for functions of one argument: df/da * da/dx
+	a := n.args[0]
+	var d Node
+	switch n.name {
+	case hash.Sin:
+		d = &Func{name: hash.Cos, args: []Node{a}} // cos(a)
+	case hash.Cos:
+		d = &UnaryExpr{op: MinusOp, a: &Func{name: hash.Sin, args: []Node{a}}} // -sin(a)
+	case hash.Tan:
+		d = &Expr{ // 1/cos(a)^2
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: PowerOp,
+				l:  &Func{name: hash.Cos, args: []Node{a}},
+				r:  TwoNode,
+			},
 		}
 	case hash.Arcsin:
-		return &Expr{ // 1/sqrt(1-a^2) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
+		d = &Expr{ // 1/sqrt(1-a^2)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Func{name: hash.Sqrt, args: []Node{&Expr{
+				op: SubtractOp,
 				l:  OneNode,
-				r: &Func{name: hash.Sqrt, a: &Expr{
-					op: SubtractOp,
-					l:  OneNode,
-					r:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-				}},
-			},
-			r: n.a.Derivative(),
+				r:  &Expr{op: PowerOp, l: a, r: TwoNode},
+			}}},
 		}
 	case hash.Arccos:
-		return &Expr{ // -1/sqrt(1-a^2) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
-				l:  MinusOneNode,
-				r: &Func{name: hash.Sqrt, a: &Expr{
-					op: SubtractOp,
-					l:  OneNode,
-					r:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-				}},
-			},
-			r: n.a.Derivative(),
+		d = &Expr{ // -1/sqrt(1-a^2)
+			op: DivideOp,
+			l:  MinusOneNode,
+			r: &Func{name: hash.Sqrt, args: []Node{&Expr{
+				op: SubtractOp,
+				l:  OneNode,
+				r:  &Expr{op: PowerOp, l: a, r: TwoNode},
+			}}},
 		}
 	case hash.Arctan:
-		return &Expr{ // 1/(1+a^2) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
+		d = &Expr{ // 1/(1+a^2)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: AddOp,
 				l:  OneNode,
-				r: &Expr{
-					op: AddOp,
-					l:  OneNode,
-					r:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-				},
+				r:  &Expr{op: PowerOp, l: a, r: TwoNode},
 			},
-			r: n.a.Derivative(),
 		}
 	case hash.Sinh:
-		return &Expr{ // cosh(a) * da/dx
-			op: MultiplyOp,
-			l:  &Func{name: hash.Cosh, a: n.a},
-			r:  n.a.Derivative(),
-		}
+		d = &Func{name: hash.Cosh, args: []Node{a}} // cosh(a)
 	case hash.Cosh:
-		return &Expr{ // sinh(a) * da/dx
-			op: MultiplyOp,
-			l:  &Func{name: hash.Sinh, a: n.a},
-			r:  n.a.Derivative(),
-		}
+		d = &Func{name: hash.Sinh, args: []Node{a}} // sinh(a)
 	case hash.Tanh:
-		return &Expr{ // 1/cosh(a)^2 * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
-				l:  OneNode,
-				r: &Expr{
-					op: PowerOp,
-					l:  &Func{name: hash.Cosh, a: n.a},
-					r:  TwoNode,
-				},
+		d = &Expr{ // 1/cosh(a)^2
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: PowerOp,
+				l:  &Func{name: hash.Cosh, args: []Node{a}},
+				r:  TwoNode,
 			},
-			r: n.a.Derivative(),
 		}
 	case hash.Arcsinh:
-		return &Expr{ // 1/sqrt(a^2+1) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
-				l:  OneNode,
-				r: &Func{name: hash.Sqrt, a: &Expr{
-					op: AddOp,
-					l:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-					r:  OneNode,
-				}},
-			},
-			r: n.a.Derivative(),
+		d = &Expr{ // 1/sqrt(a^2+1)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Func{name: hash.Sqrt, args: []Node{&Expr{
+				op: AddOp,
+				l:  &Expr{op: PowerOp, l: a, r: TwoNode},
+				r:  OneNode,
+			}}},
 		}
 	case hash.Arccosh:
-		return &Expr{ // 1/sqrt(a^2-1) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
-				l:  OneNode,
-				r: &Func{name: hash.Sqrt, a: &Expr{
-					op: SubtractOp,
-					l:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-					r:  OneNode,
-				}},
-			},
-			r: n.a.Derivative(),
+		d = &Expr{ // 1/sqrt(a^2-1)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Func{name: hash.Sqrt, args: []Node{&Expr{
+				op: SubtractOp,
+				l:  &Expr{op: PowerOp, l: a, r: TwoNode},
+				r:  OneNode,
+			}}},
 		}
 	case hash.Arctanh:
-		return &Expr{ // 1/(1-a^2) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
+		d = &Expr{ // 1/(1-a^2)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: SubtractOp,
 				l:  OneNode,
-				r: &Expr{
-					op: SubtractOp,
-					l:  OneNode,
-					r:  &Expr{op: PowerOp, l: n.a, r: TwoNode},
-				},
+				r:  &Expr{op: PowerOp, l: a, r: TwoNode},
 			},
-			r: n.a.Derivative(),
 		}
 	case hash.Sqrt:
-		return &Expr{ // 1/(2*sqrt(a)) * da/dx
-			op: MultiplyOp,
-			l: &Expr{
-				op: DivideOp,
-				l:  OneNode,
-				r: &Expr{
-					op: MultiplyOp,
-					l:  TwoNode,
-					r:  &Func{name: hash.Sqrt, a: n.a},
-				},
+		d = &Expr{ // 1/(2*sqrt(a))
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: MultiplyOp,
+				l:  TwoNode,
+				r:  &Func{name: hash.Sqrt, args: []Node{a}},
 			},
-			r: n.a.Derivative(),
 		}
 	case hash.Log:
-		return &Expr{
-			op: MultiplyOp,
-			l:  &Expr{op: DivideOp, l: OneNode, r: n.a},
-			r:  n.a.Derivative(),
-		}
+		d = &Expr{op: DivideOp, l: OneNode, r: a} // 1/a
 	case hash.Log10:
-		return &Expr{ // 1/(a*ln(10)) * da/dx
+		d = &Expr{ // 1/(a*ln(10))
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: MultiplyOp,
+				l:  a,
+				r:  &Func{name: hash.Log, args: []Node{&Number{val: 10 + 0i}}},
+			},
+		}
+	case hash.Log2:
+		d = &Expr{ // 1/(a*ln(2))
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: MultiplyOp,
+				l:  a,
+				r:  &Func{name: hash.Log, args: []Node{TwoNode}},
+			},
+		}
+	case hash.Cbrt:
+		d = &Expr{ // 1/(3*cbrt(a)^2)
+			op: DivideOp,
+			l:  OneNode,
+			r: &Expr{
+				op: MultiplyOp,
+				l:  &Number{val: 3 + 0i},
+				r: &Expr{
+					op: PowerOp,
+					l:  &Func{name: hash.Cbrt, args: []Node{a}},
+					r:  TwoNode,
+				},
+			},
+		}
+	case hash.Erf:
+		d = &Expr{ // 2/sqrt(pi) * e^(-a^2)
 			op: MultiplyOp,
 			l: &Expr{
 				op: DivideOp,
-				l:  OneNode,
-				r: &Expr{
-					op: MultiplyOp,
-					l:  n.a,
-					r:  &Func{name: hash.Log, a: &Number{val: 10 + 0i}},
-				},
+				l:  TwoNode,
+				r:  &Func{name: hash.Sqrt, args: []Node{&Variable{name: "pi", pos: -1}}},
 			},
-			r: n.a.Derivative(),
+			r: &Expr{
+				op: PowerOp,
+				l:  &Variable{name: "e", pos: -1},
+				r:  &UnaryExpr{op: MinusOp, a: &Expr{op: PowerOp, l: a, r: TwoNode}},
+			},
+		}
+	case hash.Gamma:
+		d = &Expr{ // gamma(a) * digamma(a)
+			op: MultiplyOp,
+			l:  n,
+			r:  &Func{name: hash.Digamma, args: []Node{a}},
 		}
+	case hash.Digamma:
+		d = &Func{name: hash.Polygamma, args: []Node{OneNode, a}} // polygamma(1,a)
+	case hash.Abs:
+		d = &Expr{op: DivideOp, l: a, r: n} // a/abs(a), undefined at zero
 	default:
 		panic("unknown function")
 	}
+	return &Expr{
+		op: MultiplyOp,
+		l:  d,
+		r:  a.Derivative(x),
+	}
 }
 
-func (n *Func) Calc(x complex128, vars Vars) (complex128, error) {
-	y, err := n.a.Calc(x, vars)
-	if err != nil {
-		return cmplx.NaN(), err
+func (n *Func) Calc(vars Vars) (complex128, error) {
+	ys := make([]complex128, len(n.args))
+	for i, arg := range n.args {
+		y, err := arg.Calc(vars)
+		if err != nil {
+			return cmplx.NaN(), err
+		}
+		ys[i] = y
+	}
+	return calcFunc(n.name, ys)
+}
+
+// calcFunc applies the function name to the evaluated arguments ys.
+func calcFunc(name hash.Hash, ys []complex128) (complex128, error) {
+	switch name {
+	case hash.Atan2:
+		if imag(ys[0]) == 0.0 && imag(ys[1]) == 0.0 {
+			return complex(math.Atan2(real(ys[0]), real(ys[1])), 0.0), nil
+		}
+		// atan2(y,x) = -i * log((x+iy) / sqrt(x^2+y^2))
+		return -1i * cmplx.Log((ys[1]+1i*ys[0])/cmplx.Sqrt(ys[1]*ys[1]+ys[0]*ys[0])), nil
+	case hash.Log:
+		if len(ys) == 2 {
+			return cmplx.Log(ys[1]) / cmplx.Log(ys[0]), nil
+		}
+	case hash.Polygamma:
+		m := real(ys[0])
+		if imag(ys[0]) != 0.0 || m < 0.0 || m != math.Trunc(m) {
+			return cmplx.NaN(), fmt.Errorf("order of function 'polygamma' must be a non-negative integer")
+		} else if isPole(ys[1]) {
+			return cmplx.NaN(), domainErrorf(name.String(), "%s of non-positive integer", name)
+		}
+		return polygamma(int(m), ys[1]), nil
+	case hash.Gamma, hash.Digamma:
+		if isPole(ys[0]) {
+			return cmplx.NaN(), domainErrorf(name.String(), "%s of non-positive integer", name)
+		}
+	case hash.Abs:
+		return complex(cmplx.Abs(ys[0]), 0.0), nil
+	case hash.Max, hash.Min:
+		y := ys[0]
+		for _, yi := range ys {
+			if imag(yi) != 0.0 {
+				return cmplx.NaN(), fmt.Errorf("complex argument to function '%s'", name)
+			}
+			if name == hash.Max && real(y) < real(yi) || name == hash.Min && real(yi) < real(y) {
+				y = yi
+			}
+		}
+		return y, nil
 	}
 
 	var f func(complex128) complex128
-	switch n.name {
+	switch name {
 	case hash.Sin:
 		f = cmplx.Sin
 	case hash.Cos:
@@ -492,8 +856,143 @@ func (n *Func) Calc(x complex128, vars Vars) (complex128, error) {
 		f = cmplx.Log
 	case hash.Log10:
 		f = cmplx.Log10
+	case hash.Log2:
+		f = log2
+	case hash.Cbrt:
+		f = cbrt
+	case hash.Erf:
+		f = erf
+	case hash.Gamma:
+		f = gamma
+	case hash.Digamma:
+		f = digamma
+	default:
+		return cmplx.NaN(), fmt.Errorf("unknown function '%s'", name)
+	}
+	return f(ys[0]), nil
+}
+
+func (n *Func) CalcReal(vars Vars) (float64, error) {
+	ys := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		y, err := arg.CalcReal(vars)
+		if err != nil {
+			return math.NaN(), err
+		}
+		ys[i] = y
+	}
+	return calcFuncReal(n.name, ys)
+}
+
+// calcFuncReal applies the function name to the evaluated real arguments ys, returning a DomainError when the result is not real.
+func calcFuncReal(name hash.Hash, ys []float64) (float64, error) {
+	switch name {
+	case hash.Atan2:
+		return math.Atan2(ys[0], ys[1]), nil
+	case hash.Log:
+		if len(ys) == 2 {
+			if ys[0] <= 0.0 {
+				return math.NaN(), domainErrorf(name.String(), "%s with non-positive base", name)
+			} else if ys[1] < 0.0 {
+				return math.NaN(), domainErrorf(name.String(), "%s of negative number", name)
+			}
+			return math.Log(ys[1]) / math.Log(ys[0]), nil
+		}
+	case hash.Polygamma:
+		if ys[0] < 0.0 || ys[0] != math.Trunc(ys[0]) {
+			return math.NaN(), fmt.Errorf("order of function 'polygamma' must be a non-negative integer")
+		} else if isPole(complex(ys[1], 0.0)) {
+			return math.NaN(), domainErrorf(name.String(), "%s of non-positive integer", name)
+		}
+		return real(polygamma(int(ys[0]), complex(ys[1], 0.0))), nil
+	case hash.Max:
+		y := ys[0]
+		for _, yi := range ys[1:] {
+			y = math.Max(y, yi)
+		}
+		return y, nil
+	case hash.Min:
+		y := ys[0]
+		for _, yi := range ys[1:] {
+			y = math.Min(y, yi)
+		}
+		return y, nil
+	}
+
+	y := ys[0]
+	switch name {
+	case hash.Arcsin, hash.Arccos:
+		if y < -1.0 || 1.0 < y {
+			return math.NaN(), domainErrorf(name.String(), "%s of number outside [-1,1]", name)
+		}
+	case hash.Arccosh:
+		if y < 1.0 {
+			return math.NaN(), domainErrorf(name.String(), "%s of number smaller than 1", name)
+		}
+	case hash.Arctanh:
+		if y < -1.0 || 1.0 < y {
+			return math.NaN(), domainErrorf(name.String(), "%s of number outside [-1,1]", name)
+		}
+	case hash.Sqrt:
+		if y < 0.0 {
+			return math.NaN(), domainErrorf(name.String(), "%s of negative number", name)
+		}
+	case hash.Log, hash.Log10, hash.Log2:
+		if y < 0.0 {
+			return math.NaN(), domainErrorf(name.String(), "%s of negative number", name)
+		}
+	case hash.Gamma, hash.Digamma:
+		if isPole(complex(y, 0.0)) {
+			return math.NaN(), domainErrorf(name.String(), "%s of non-positive integer", name)
+		}
+	}
+
+	var f func(float64) float64
+	switch name {
+	case hash.Sin:
+		f = math.Sin
+	case hash.Cos:
+		f = math.Cos
+	case hash.Tan:
+		f = math.Tan
+	case hash.Arcsin:
+		f = math.Asin
+	case hash.Arccos:
+		f = math.Acos
+	case hash.Arctan:
+		f = math.Atan
+	case hash.Sinh:
+		f = math.Sinh
+	case hash.Cosh:
+		f = math.Cosh
+	case hash.Tanh:
+		f = math.Tanh
+	case hash.Arcsinh:
+		f = math.Asinh
+	case hash.Arccosh:
+		f = math.Acosh
+	case hash.Arctanh:
+		f = math.Atanh
+	case hash.Sqrt:
+		f = math.Sqrt
+	case hash.Log:
+		f = math.Log
+	case hash.Log10:
+		f = math.Log10
+	case hash.Log2:
+		f = math.Log2
+	case hash.Cbrt:
+		f = math.Cbrt
+	case hash.Erf:
+		f = math.Erf
+	case hash.Gamma:
+		f = math.Gamma
+	case hash.Digamma:
+		return real(digamma(complex(y, 0.0))), nil
+	case hash.Abs:
+		f = math.Abs
 	default:
-		return cmplx.NaN(), fmt.Errorf("unknown function '%s'", n.name)
+		return math.NaN(), fmt.Errorf("unknown function '%s'", name)
 	}
 	return f(y), nil
 }
@@ -513,12 +1012,26 @@ func (n *Expr) String() string {
 	}
 
 	r := n.r.String()
-	if rExpr, ok := n.r.(*Expr); ok && (OpPrec[n.op] > OpPrec[rExpr.op] || !OpRightAssoc[n.op] && OpPrec[n.op] == OpPrec[rExpr.op] && n.op != rExpr.op) {
+	if rExpr, ok := n.r.(*Expr); ok && (OpPrec[n.op] > OpPrec[rExpr.op] || !OpRightAssoc[n.op] && OpPrec[n.op] == OpPrec[rExpr.op] && (n.op != rExpr.op || n.op == ModOp)) {
 		r = "(" + r + ")"
 	}
 	return fmt.Sprintf("%s%v%s", l, n.op, r)
 }
 
+// opLaTeX holds the LaTeX notation of operators that differ from their string representation.
+var opLaTeX = map[Operator]string{
+	LessOp:         " < ",
+	LessEqualOp:    " \\leq ",
+	GreaterOp:      " > ",
+	GreaterEqualOp: " \\geq ",
+	EqualOp:        " = ",
+	NotEqualOp:     " \\neq ",
+	AndOp:          " \\land ",
+	OrOp:           " \\lor ",
+	NotOp:          "\\lnot ",
+	ModOp:          " \\bmod ",
+}
+
 func (n *Expr) LaTeX() string {
 	l := n.l.LaTeX()
 	if lExpr, ok := n.l.(*Expr); ok && (OpPrec[n.op] > OpPrec[lExpr.op] || OpRightAssoc[n.op] && OpPrec[n.op] == OpPrec[lExpr.op]) {
@@ -528,11 +1041,13 @@ func (n *Expr) LaTeX() string {
 	r := n.r.LaTeX()
 	if n.op == PowerOp {
 		r = "{" + r + "}"
-	} else if rExpr, ok := n.r.(*Expr); ok && (OpPrec[n.op] > OpPrec[rExpr.op] || !OpRightAssoc[n.op] && OpPrec[n.op] == OpPrec[rExpr.op] && n.op != rExpr.op) {
+	} else if rExpr, ok := n.r.(*Expr); ok && (OpPrec[n.op] > OpPrec[rExpr.op] || !OpRightAssoc[n.op] && OpPrec[n.op] == OpPrec[rExpr.op] && (n.op != rExpr.op || n.op == ModOp)) {
 		r = "\\left(" + r + "\\right)"
 	}
 
-	if n.op == DivideOp {
+	if op, ok := opLaTeX[n.op]; ok {
+		return fmt.Sprintf("%s%s%s", l, op, r)
+	} else if n.op == DivideOp {
 		return fmt.Sprintf("\\frac{%s}{%s}", l, r)
 	} else if n.op == MultiplyOp {
 		// TODO: do more checking for when we have to add the multiplier explicitly
@@ -548,19 +1063,21 @@ func (n *Expr) Equal(iother Node) bool {
 	return ok && n.op == other.op && n.l.Equal(other.l) && n.r.Equal(other.r)
 }
 
-func (n *Expr) Derivative() Node {
+func (n *Expr) Derivative(x string) Node {
 	switch n.op {
+	case LessOp, LessEqualOp, GreaterOp, GreaterEqualOp, EqualOp, NotEqualOp, AndOp, OrOp:
+		return ZeroNode // piecewise constant
 	case AddOp:
 		return &Expr{
 			op: AddOp,
-			l:  n.l.Derivative(),
-			r:  n.r.Derivative(),
+			l:  n.l.Derivative(x),
+			r:  n.r.Derivative(x),
 		}
 	case SubtractOp:
 		return &Expr{
 			op: SubtractOp,
-			l:  n.l.Derivative(),
-			r:  n.r.Derivative(),
+			l:  n.l.Derivative(x),
+			r:  n.r.Derivative(x),
 		}
 	case MultiplyOp:
 		return &Expr{ // r * dl/dx + l * dr/dx
@@ -568,12 +1085,12 @@ func (n *Expr) Derivative() Node {
 			l: &Expr{
 				op: MultiplyOp,
 				l:  n.r,
-				r:  n.l.Derivative(),
+				r:  n.l.Derivative(x),
 			},
 			r: &Expr{
 				op: MultiplyOp,
 				l:  n.l,
-				r:  n.r.Derivative(),
+				r:  n.r.Derivative(x),
 			},
 		}
 	case DivideOp:
@@ -584,12 +1101,12 @@ func (n *Expr) Derivative() Node {
 				l: &Expr{
 					op: MultiplyOp,
 					l:  n.r,
-					r:  n.l.Derivative(),
+					r:  n.l.Derivative(x),
 				},
 				r: &Expr{
 					op: MultiplyOp,
 					l:  n.l,
-					r:  n.r.Derivative(),
+					r:  n.r.Derivative(x),
 				},
 			},
 			r: &Expr{ // r^2
@@ -598,6 +1115,20 @@ func (n *Expr) Derivative() Node {
 				r:  TwoNode,
 			},
 		}
+	case ModOp:
+		return &Expr{ // dl/dx - dr/dx * floor(l/r), with floor(l/r) = (l - l%r) / r
+			op: SubtractOp,
+			l:  n.l.Derivative(x),
+			r: &Expr{
+				op: MultiplyOp,
+				l:  n.r.Derivative(x),
+				r: &Expr{
+					op: DivideOp,
+					l:  &Expr{op: SubtractOp, l: n.l, r: n},
+					r:  n.r,
+				},
+			},
+		}
 	case PowerOp:
 		return &Expr{
 			op: AddOp,
@@ -616,7 +1147,7 @@ func (n *Expr) Derivative() Node {
 						},
 					},
 				},
-				r: n.l.Derivative(),
+				r: n.l.Derivative(x),
 			},
 			r: &Expr{ // l^r * ln(l) * dr/dx
 				op: MultiplyOp,
@@ -625,10 +1156,10 @@ func (n *Expr) Derivative() Node {
 					l:  n,
 					r: &Func{
 						name: hash.Log,
-						a:    n.l,
+						args: []Node{n.l},
 					},
 				},
-				r: n.r.Derivative(),
+				r: n.r.Derivative(x),
 			},
 		}
 	default:
@@ -636,19 +1167,23 @@ func (n *Expr) Derivative() Node {
 	}
 }
 
-func (n *Expr) Calc(x complex128, vars Vars) (complex128, error) {
-	l, err := n.l.Calc(x, vars)
+func (n *Expr) Calc(vars Vars) (complex128, error) {
+	l, err := n.l.Calc(vars)
 	if err != nil {
 		return cmplx.NaN(), err
 	}
 
-	r, err := n.r.Calc(x, vars)
+	r, err := n.r.Calc(vars)
 	if err != nil {
 		return cmplx.NaN(), err
 	}
+	return calcOp(n.op, l, r)
+}
 
+// calcOp applies the binary operator op to the evaluated operands l and r.
+func calcOp(op Operator, l, r complex128) (complex128, error) {
 	var y complex128
-	switch n.op {
+	switch op {
 	case AddOp:
 		y = l + r
 	case SubtractOp:
@@ -662,8 +1197,91 @@ func (n *Expr) Calc(x complex128, vars Vars) (complex128, error) {
 		y = l / r
 	case PowerOp:
 		y = cmplx.Pow(l, r)
+	case ModOp:
+		if imag(l) != 0.0 || imag(r) != 0.0 {
+			return cmplx.NaN(), fmt.Errorf("modulo of complex numbers")
+		}
+		yr, err := calcOpReal(op, real(l), real(r))
+		if err != nil {
+			return cmplx.NaN(), err
+		}
+		y = complex(yr, 0.0)
+	case EqualOp:
+		y = boolNumber(l == r)
+	case NotEqualOp:
+		y = boolNumber(l != r)
+	case AndOp:
+		y = boolNumber(l != 0.0 && r != 0.0)
+	case OrOp:
+		y = boolNumber(l != 0.0 || r != 0.0)
+	case LessOp, LessEqualOp, GreaterOp, GreaterEqualOp:
+		if imag(l) != 0.0 || imag(r) != 0.0 {
+			return cmplx.NaN(), fmt.Errorf("cannot compare complex numbers")
+		}
+		yr, _ := calcOpReal(op, real(l), real(r))
+		y = complex(yr, 0.0)
+	default:
+		return cmplx.NaN(), fmt.Errorf("unknown operation '%s'", op)
+	}
+	return y, nil
+}
+
+func (n *Expr) CalcReal(vars Vars) (float64, error) {
+	l, err := n.l.CalcReal(vars)
+	if err != nil {
+		return math.NaN(), err
+	}
+
+	r, err := n.r.CalcReal(vars)
+	if err != nil {
+		return math.NaN(), err
+	}
+	return calcOpReal(n.op, l, r)
+}
+
+// calcOpReal applies the binary operator op to the evaluated real operands l and r, returning a DomainError when the result is not real.
+func calcOpReal(op Operator, l, r float64) (float64, error) {
+	var y float64
+	switch op {
+	case AddOp:
+		y = l + r
+	case SubtractOp:
+		y = l - r
+	case MultiplyOp:
+		y = l * r
+	case DivideOp:
+		if r == 0 {
+			return math.NaN(), fmt.Errorf("division by zero")
+		}
+		y = l / r
+	case PowerOp:
+		if l < 0.0 && r != math.Trunc(r) {
+			return math.NaN(), domainErrorf(op.String(), "non-integer power of negative number")
+		}
+		y = math.Pow(l, r)
+	case ModOp:
+		if r == 0 {
+			return math.NaN(), fmt.Errorf("modulo by zero")
+		}
+		y = floorMod(l, r)
+	case LessOp:
+		y = real(boolNumber(l < r))
+	case LessEqualOp:
+		y = real(boolNumber(l <= r))
+	case GreaterOp:
+		y = real(boolNumber(l > r))
+	case GreaterEqualOp:
+		y = real(boolNumber(l >= r))
+	case EqualOp:
+		y = real(boolNumber(l == r))
+	case NotEqualOp:
+		y = real(boolNumber(l != r))
+	case AndOp:
+		y = real(boolNumber(l != 0.0 && r != 0.0))
+	case OrOp:
+		y = real(boolNumber(l != 0.0 || r != 0.0))
 	default:
-		return cmplx.NaN(), fmt.Errorf("unknown operation '%s'", n.op)
+		return math.NaN(), fmt.Errorf("unknown operation '%s'", op)
 	}
 	return y, nil
 }
@@ -675,18 +1293,44 @@ type UnaryExpr struct {
 	a  Node
 }
 
+// factorialIsGroup returns true if the operand of a factorial needs parentheses, which are expressions, prefix operators and negative or complex numbers.
+func factorialIsGroup(n Node) bool {
+	if nUnaryExpr, ok := n.(*UnaryExpr); ok {
+		return nUnaryExpr.op != FactorialOp
+	} else if nNumber, ok := n.(*Number); ok {
+		return real(nNumber.val) < 0.0 || imag(nNumber.val) != 0.0
+	}
+	return nodeIsGroup(n)
+}
+
 func (n *UnaryExpr) String() string {
-	if nodeIsGroup(n.a) {
+	if n.op == FactorialOp {
+		if factorialIsGroup(n.a) {
+			return fmt.Sprintf("(%v)!", n.a)
+		}
+		return fmt.Sprintf("%v!", n.a)
+	} else if nodeIsGroup(n.a) {
 		return fmt.Sprintf("%v(%v)", n.op, n.a)
 	}
 	return fmt.Sprintf("%v%v", n.op, n.a)
 }
 
 func (n *UnaryExpr) LaTeX() string {
+	if n.op == FactorialOp {
+		if factorialIsGroup(n.a) {
+			return fmt.Sprintf("\\left(%s\\right)!", n.a.LaTeX())
+		}
+		return fmt.Sprintf("%s!", n.a.LaTeX())
+	}
+
+	op := n.op.String()
+	if s, ok := opLaTeX[n.op]; ok {
+		op = s
+	}
 	if nodeIsGroup(n.a) {
-		return fmt.Sprintf("%v\\left(%s\\right)", n.op, n.a.LaTeX())
+		return fmt.Sprintf("%s\\left(%s\\right)", op, n.a.LaTeX())
 	}
-	return fmt.Sprintf("%v%s", n.op, n.a.LaTeX())
+	return fmt.Sprintf("%s%s", op, n.a.LaTeX())
 }
 
 func (n *UnaryExpr) Equal(iother Node) bool {
@@ -694,22 +1338,64 @@ func (n *UnaryExpr) Equal(iother Node) bool {
 	return ok && n.op == other.op && n.a.Equal(other.a)
 }
 
-func (n *UnaryExpr) Derivative() Node {
-	return &UnaryExpr{op: n.op, a: n.a.Derivative()}
+func (n *UnaryExpr) Derivative(x string) Node {
+	if n.op == NotOp {
+		return ZeroNode // piecewise constant
+	} else if n.op == FactorialOp {
+		return &Expr{ // a! * digamma(a+1) * da/dx
+			op: MultiplyOp,
+			l: &Expr{
+				op: MultiplyOp,
+				l:  n,
+				r:  &Func{name: hash.Digamma, args: []Node{&Expr{op: AddOp, l: n.a, r: OneNode}}},
+			},
+			r: n.a.Derivative(x),
+		}
+	}
+	return &UnaryExpr{op: n.op, a: n.a.Derivative(x)}
 }
 
-func (n *UnaryExpr) Calc(x complex128, vars Vars) (complex128, error) {
-	y, err := n.a.Calc(x, vars)
+func (n *UnaryExpr) Calc(vars Vars) (complex128, error) {
+	y, err := n.a.Calc(vars)
 	if err != nil {
 		return cmplx.NaN(), err
 	}
-	return -y, nil
+	return calcUnary(n.op, y)
+}
+
+// calcUnary applies the unary operator op to the evaluated operand y.
+func calcUnary(op Operator, y complex128) (complex128, error) {
+	switch op {
+	case NotOp:
+		return boolNumber(y == 0.0), nil
+	case FactorialOp:
+		if isPole(y + 1.0) {
+			return cmplx.NaN(), domainErrorf(op.String(), "factorial of negative integer")
+		}
+		return factorial(y), nil
+	case MinusOp:
+		return -y, nil
+	}
+	return cmplx.NaN(), fmt.Errorf("unknown operation '%s'", op)
+}
+
+func (n *UnaryExpr) CalcReal(vars Vars) (float64, error) {
+	y, err := n.a.CalcReal(vars)
+	if err != nil {
+		return math.NaN(), err
+	}
+	yc, err := calcUnary(n.op, complex(y, 0.0))
+	if err != nil {
+		return math.NaN(), err
+	}
+	return real(yc), nil
 }
 
 ////////////////
 
 type Variable struct {
 	name string
+	pos  int // offset in the parsed input, or -1 if not parsed
 }
 
 func (n *Variable) String() string {
@@ -725,20 +1411,29 @@ func (n *Variable) Equal(iother Node) bool {
 	return ok && n.name == other.name
 }
 
-func (n *Variable) Derivative() Node {
-	if n.name == "x" {
+func (n *Variable) Derivative(x string) Node {
+	if n.name == x {
 		return OneNode
 	}
 	return ZeroNode
 }
 
-func (n *Variable) Calc(x complex128, vars Vars) (complex128, error) {
-	if n.name == "x" {
-		return x, nil
-	} else if _, ok := vars[n.name]; !ok {
+func (n *Variable) Calc(vars Vars) (complex128, error) {
+	y, ok := vars[n.name]
+	if !ok {
 		return cmplx.NaN(), fmt.Errorf("undefined variable '%s'", n.name)
 	}
-	return vars[n.name], nil
+	return y, nil
+}
+
+func (n *Variable) CalcReal(vars Vars) (float64, error) {
+	y, ok := vars[n.name]
+	if !ok {
+		return math.NaN(), fmt.Errorf("undefined variable '%s'", n.name)
+	} else if imag(y) != 0.0 {
+		return math.NaN(), domainErrorf("", "variable '%s' is not real", n.name)
+	}
+	return real(y), nil
 }
 
 ////////////////
@@ -766,10 +1461,17 @@ func (n *Number) Equal(iother Node) bool {
 	return ok && n.val == other.val
 }
 
-func (n *Number) Derivative() Node {
+func (n *Number) Derivative(x string) Node {
 	return ZeroNode
 }
 
-func (n *Number) Calc(x complex128, vars Vars) (complex128, error) {
+func (n *Number) Calc(vars Vars) (complex128, error) {
 	return n.val, nil
 }
+
+func (n *Number) CalcReal(vars Vars) (float64, error) {
+	if imag(n.val) != 0.0 {
+		return math.NaN(), domainErrorf("", "number %v is not real", n)
+	}
+	return real(n.val), nil
+}
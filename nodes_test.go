@@ -19,6 +19,8 @@ func TestOptimize(t *testing.T) {
 		{"2*-1", "-2"},
 		{"2/1", "2"},
 		{"2/-1", "-2"},
+		{"0/x", "0/x"},
+		{"0/0", "0/0"},
 		{"2^0", "1"},
 		{"2^1", "2"},
 		{"a^-1", "1/a"},
@@ -38,14 +40,37 @@ func TestOptimize(t *testing.T) {
 		{"cos(-1)", "cos(1)"},
 		{"tan(-1)", "-tan(1)"},
 		{"5+(-1/x)", "5-1/x"},
-		{"x+x", "2*x"},
+		{"x+x", "x+x"}, // like terms are collected by Collect
 		{"-a*-b", "a*b"},
 		{"a*-b", "-(a*b)"},
 		{"(-a)^2", "a^2"},
 		{"(-a)^3", "-(a^3)"},
 		{"x*2", "2*x"},
-		{"2*(x*3)", "6*x"},
-		{"(2*x)*3", "6*x"},
+		{"2*(x*3)", "2*3*x"},
+		{"(2*x)*3", "3*2*x"},
+		{"cbrt(-8)", "-2"},
+		{"cbrt(-x)", "-cbrt(x)"},
+		{"log2(8)", "3"},
+		{"2^log2(x)", "x"},
+		{"erf(0)", "0"},
+		{"erf(-x)", "-erf(x)"},
+		{"gamma(5)", "24"},
+		{"gamma(23)", "1.1240007277776077e+21"},
+		{"gamma(24)", "gamma(24)"},
+		{"gamma(0)", "gamma(0)"},
+		{"polygamma(0, x)", "digamma(x)"},
+		{"|-3|", "3"},
+		{"|-x|", "abs(x)"},
+		{"||x||", "abs(x)"},
+		{"5!", "120"},
+		{"0!", "1"},
+		{"22!", "1.1240007277776077e+21"},
+		{"23!", "23!"},
+		{"0.5!", "0.5!"},
+		{"(-1)!", "(-1)!"},
+		{"7%3", "1"},
+		{"-7%3", "2"},
+		{"x%0", "x%0"},
 	}
 
 	for _, test := range tests {
@@ -73,6 +98,18 @@ func TestDerivative(t *testing.T) {
 		{"2^x", "2^x*log(2)"},
 		{"e^x", "e^x"},
 		{"ln x", "1/x"},
+		{"atan2(1, x)", "-(1/(x^2+1))"},
+		{"max(x, 2)", "(1+(x-2)/sqrt((x-2)^2))/2"},
+		{"cbrt x", "1/(3*cbrt(x)^2)"},
+		{"log2 x", "1/(x*log(2))"},
+		{"erf x", "2/sqrt(pi)*(1/e^x^2)"},
+		{"gamma x", "gamma(x)*digamma(x)"},
+		{"digamma x", "polygamma(1,x)"},
+		{"polygamma(2, x)", "polygamma(3,x)"},
+		{"|x|", "x/abs(x)"},
+		{"x!", "x!*digamma(x+1)"},
+		{"x%3", "1"},
+		{"3%x", "-((3-3%x)/x)"},
 	}
 
 	for _, test := range tests {
@@ -88,3 +125,55 @@ func TestDerivative(t *testing.T) {
 		})
 	}
 }
+
+func TestDerivativeWith(t *testing.T) {
+	tests := []struct {
+		in   string
+		name string
+		out  string
+	}{
+		{"x", "y", "0"},
+		{"y", "y", "1"},
+		{"a*x^2", "a", "x^2"},
+		{"sin(a*x)", "a", "cos(a*x)*x"},
+		{"x*y^2", "y", "x*2*y"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(f, errs)
+			}
+			df := f.DerivativeWith(test.name)
+			if df.String() != test.out {
+				t.Fatal(df.String(), "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestOptimizeNonDestructive(t *testing.T) {
+	tests := []string{
+		"x*2",
+		"(x+0)*(1*y)",
+		"-(-x)+sin(0*x)",
+		"max(1, 2)+polygamma(0, x)",
+		"2*(x*3)",
+		"(-x)^2",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			f, errs := Parse(test)
+			if len(errs) > 0 {
+				t.Fatal(f, errs)
+			}
+			want := f.root.String()
+			Optimize(f.root)
+			if f.root.String() != want {
+				t.Fatal(f.root.String(), "!=", want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type SYToken struct {
 	data     []byte
 	op       Operator
 	function hash.Hash
+	nargs    int // number of comma-separated arguments for OpenOp and FuncOp
 }
 
 func (t SYToken) String() string {
@@ -25,17 +26,33 @@ func (t SYToken) String() string {
 }
 
 var OpPrec = map[Operator]int{
-	FuncOp:     1,
-	MultiplyOp: 2,
-	DivideOp:   2,
-	PowerOp:    3,
-	MinusOp:    4,
+	QuestionOp:     -4,
+	ColonOp:        -4,
+	OrOp:           -3,
+	AndOp:          -2,
+	LessOp:         -1,
+	LessEqualOp:    -1,
+	GreaterOp:      -1,
+	GreaterEqualOp: -1,
+	EqualOp:        -1,
+	NotEqualOp:     -1,
+	FuncOp:         1,
+	MultiplyOp:     2,
+	DivideOp:       2,
+	ModOp:          2,
+	PowerOp:        3,
+	MinusOp:        4,
+	NotOp:          4,
+	FactorialOp:    5,
 }
 
 var OpRightAssoc = map[Operator]bool{
-	PowerOp: true,
-	MinusOp: true,
-	FuncOp:  true,
+	PowerOp:    true,
+	MinusOp:    true,
+	NotOp:      true,
+	FuncOp:     true,
+	QuestionOp: true,
+	ColonOp:    true,
 }
 
 type ParseError struct {
@@ -63,7 +80,18 @@ type Parser struct {
 	operatorStack []SYToken
 }
 
+// ParseOptions are the options of ParseWithOptions.
+type ParseOptions struct {
+	RejectUnknown bool     // reject variables that are not known
+	Known         []string // names of known variables besides the default variables, such as e and pi
+}
+
 func Parse(in string) (*Function, []error) {
+	return ParseWithOptions(in, nil)
+}
+
+// ParseWithOptions parses a formula like Parse. If opts.RejectUnknown is set, it returns an error for each occurrence of a variable that is neither a default variable nor in opts.Known.
+func ParseWithOptions(in string, opts *ParseOptions) (*Function, []error) {
 	var errs []error
 	l := NewLexer(strings.NewReader(in))
 	p := Parser{}
@@ -83,20 +111,30 @@ LOOP:
 			errs = append(errs, ParseErrorf(l.Pos(), "bad input"))
 			break LOOP
 		case NumericToken:
-			p.output = append(p.output, SYToken{l.Pos(), tt, data, 0, 0})
+			p.output = append(p.output, SYToken{l.Pos(), tt, data, 0, 0, 0})
 		case IdentifierToken:
-			p.output = append(p.output, SYToken{l.Pos(), tt, data, 0, 0})
+			p.output = append(p.output, SYToken{l.Pos(), tt, data, 0, 0, 0})
 		case OperatorToken:
 			op := l.Operator()
-			sytoken := SYToken{l.Pos(), tt, data, op, 0}
+			sytoken := SYToken{l.Pos(), tt, data, op, 0, 1}
 			switch op {
 			case FuncOp:
 				sytoken.function = l.Function()
 				p.operatorStack = append(p.operatorStack, sytoken)
-			case OpenOp:
+			case OpenOp, OpenAbsOp:
 				p.operatorStack = append(p.operatorStack, sytoken)
+			case CommaOp:
+				for len(p.operatorStack) > 0 && !p.isOpen() {
+					p.popOperation()
+				}
+				n := len(p.operatorStack)
+				if n < 2 || p.operatorStack[n-1].op != OpenOp || p.operatorStack[n-2].op != FuncOp {
+					errs = append(errs, ParseErrorf(l.Pos(), "unexpected comma"))
+					break LOOP
+				}
+				p.operatorStack[n-1].nargs++
 			case CloseOp:
-				for len(p.operatorStack) > 0 && p.operatorStack[len(p.operatorStack)-1].op != OpenOp {
+				for len(p.operatorStack) > 0 && !p.isOpen() {
 					p.popOperation()
 				}
 				n := len(p.operatorStack)
@@ -104,13 +142,41 @@ LOOP:
 					errs = append(errs, ParseErrorf(l.Pos(), "mismatched closing parentheses"))
 					break LOOP
 				} else if n > 1 && p.operatorStack[n-2].op == FuncOp {
+					p.operatorStack[n-2].nargs = p.operatorStack[n-1].nargs
 					p.popOperation()
 				}
 				p.popOperation() // pop OpenOp
+			case CloseAbsOp:
+				// turn the opening bar into the abs function
+				for len(p.operatorStack) > 0 && !p.isOpen() {
+					p.popOperation()
+				}
+				n := len(p.operatorStack)
+				if n == 0 || p.operatorStack[n-1].op != OpenAbsOp {
+					errs = append(errs, ParseErrorf(l.Pos(), "mismatched absolute value bars"))
+					break LOOP
+				}
+				p.operatorStack[n-1].op = FuncOp
+				p.operatorStack[n-1].function = hash.Abs
+				p.popOperation()
+			case FactorialOp:
+				// postfix operator that binds tighter than any operator on the stack
+				p.output = append(p.output, sytoken)
+			case ColonOp:
+				// turn the ? of the conditional into a : that takes three operands
+				for len(p.operatorStack) > 0 && p.operatorStack[len(p.operatorStack)-1].op != QuestionOp && !p.isOpen() {
+					p.popOperation()
+				}
+				n := len(p.operatorStack)
+				if n == 0 || p.operatorStack[n-1].op != QuestionOp {
+					errs = append(errs, ParseErrorf(l.Pos(), "unexpected colon"))
+					break LOOP
+				}
+				p.operatorStack[n-1].op = ColonOp
 			default:
 				for n := len(p.operatorStack); n > 0; n-- {
 					stack := p.operatorStack[n-1].op
-					if !(OpPrec[stack] > OpPrec[op] || !OpRightAssoc[stack] && OpPrec[stack] == OpPrec[op]) || stack == OpenOp {
+					if !(OpPrec[stack] > OpPrec[op] || !OpRightAssoc[stack] && OpPrec[stack] == OpPrec[op]) || stack == OpenOp || stack == OpenAbsOp {
 						break
 					}
 					p.popOperation()
@@ -139,10 +205,34 @@ LOOP:
 		return nil, []error{fmt.Errorf("some operands remain unparsed")}
 	}
 
+	if opts != nil && opts.RejectUnknown {
+		known := map[string]bool{}
+		for _, name := range opts.Known {
+			known[name] = true
+		}
+		Inspect(root, func(n Node) bool {
+			if v, ok := n.(*Variable); ok && !known[v.name] {
+				if _, ok := DefaultVars[v.name]; !ok {
+					errs = append(errs, ParseErrorf(v.pos, "unknown variable '%s'", v.name))
+				}
+			}
+			return true
+		})
+		if len(errs) != 0 {
+			return nil, errs
+		}
+	}
+
 	vars := DefaultVars.Duplicate()
 	return &Function{root: root, Vars: vars}, nil
 }
 
+// isOpen returns true if the operator on top of the stack is an opening parenthesis or bar.
+func (p *Parser) isOpen() bool {
+	op := p.operatorStack[len(p.operatorStack)-1].op
+	return op == OpenOp || op == OpenAbsOp
+}
+
 func (p *Parser) popOperation() {
 	p.output = append(p.output, p.operatorStack[len(p.operatorStack)-1])
 	p.operatorStack = p.operatorStack[:len(p.operatorStack)-1]
@@ -197,22 +287,52 @@ func (p *Parser) popNode() (Node, error) {
 		}
 		return &Number{val: complex(fr, fi)}, nil
 	case IdentifierToken:
-		return &Variable{name: string(tok.data)}, nil
+		return &Variable{name: string(tok.data), pos: tok.pos - len(tok.data)}, nil
 	case OperatorToken:
 		switch tok.op {
 		case FuncOp:
-			a, _ := p.popNode()
-			if tok.function == hash.Exp {
-				return &Expr{op: PowerOp, l: &Variable{name: "e"}, r: a}, nil
-			} else if tok.function == hash.Ln {
-				tok.function = hash.Log
+			args := make([]Node, tok.nargs)
+			for i := len(args) - 1; 0 <= i; i-- {
+				a, err := p.popNode()
+				if err == ErrNoOperand {
+					return nil, ParseErrorf(tok.pos, "function has no operands")
+				} else if err != nil {
+					return nil, err
+				}
+				args[i] = a
+			}
+
+			n, err := newFuncNode(tok.function, args)
+			if err != nil {
+				return nil, ParseErrorf(tok.pos, "%v", err)
 			}
-			return &Func{name: tok.function, a: a}, nil
+			return n, nil
 		case OpenOp:
 			return p.popNode()
-		case MinusOp:
-			a, _ := p.popNode()
+		case OpenAbsOp:
+			return nil, ParseErrorf(tok.pos, "mismatched absolute value bars")
+		case MinusOp, NotOp, FactorialOp:
+			a, err := p.popNode()
+			if err == ErrNoOperand {
+				return nil, ParseErrorf(tok.pos, "operator has no operand")
+			} else if err != nil {
+				return nil, err
+			}
 			return &UnaryExpr{op: tok.op, a: a}, nil
+		case QuestionOp:
+			return nil, ParseErrorf(tok.pos, "conditional has no colon")
+		case ColonOp:
+			args := make([]Node, 3)
+			for i := len(args) - 1; 0 <= i; i-- {
+				a, err := p.popNode()
+				if err == ErrNoOperand {
+					return nil, ParseErrorf(tok.pos, "conditional has no operands")
+				} else if err != nil {
+					return nil, err
+				}
+				args[i] = a
+			}
+			return &Piecewise{args: args}, nil
 		default:
 			r, err := p.popNode()
 			if err != nil && err != ErrNoOperand {
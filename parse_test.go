@@ -32,6 +32,33 @@ func TestParse(t *testing.T) {
 		{"5x", "5*x"},
 		{"exp(5)", "e^5"},
 		{"log10(5)", "log10(5)"},
+		{"atan2(y, x)", "atan2(y,x)"},
+		{"max(1, 2, 3)", "max(1,2,3)"},
+		{"max(sin x, -1)", "max(sin(x),-1)"},
+		{"log(2, x)", "log(2,x)"},
+		{"pow(x, 3)", "x^3"},
+		{"polygamma(1, x)", "polygamma(1,x)"},
+		{"|x|", "abs(x)"},
+		{"||x|-1|", "abs(abs(x)-1)"},
+		{"2|x|y", "2*abs(x)*y"},
+		{"|2|x||", "abs(2*abs(x))"},
+		{"|x|(y+1)", "abs(x)*(y+1)"},
+		{"|x|-|y|", "abs(x)-abs(y)"},
+		{"sin|x|", "sin(abs(x))"},
+		{"x!", "x!"},
+		{"-x!", "-x!"},
+		{"x^2!", "x^2!"},
+		{"(-x)!", "(-x)!"},
+		{"(x+1)!", "(x+1)!"},
+		{"|x|!", "abs(x)!"},
+		{"x%3", "x%3"},
+		{"mod(x, 3)", "x%3"},
+		{"x mod 3", "x%3"},
+		{"(x+1)MOD y", "(x+1)%y"},
+		{"2*mod(x, 3)", "2*(x%3)"},
+		{"x*modulus", "x*modulus"},
+		{"x*y%3", "x*y%3"},
+		{"x%(y%3)", "x%(y%3)"},
 	}
 
 	for _, test := range tests {
@@ -55,6 +82,24 @@ func TestParseErr(t *testing.T) {
 		{"", "empty formula"},
 		{"1++2", "operator has no operands"},
 		{"4&4", "bad input"},
+		{"(1,2)", "unexpected comma"},
+		{"max()", "function has no operands"},
+		{"atan2(1)", "function 'atan2' takes 2 arguments, got 1"},
+		{"sin(1,2)", "function 'sin' takes 1 argument, got 2"},
+		{"log(1,2,3)", "function 'log' takes at most 2 arguments, got 3"},
+		{"2 x", "some operands remain unparsed"},
+		{"|x", "mismatched absolute value bars"},
+		{"x|", "mismatched absolute value bars"},
+		{"|(x|)", "mismatched absolute value bars"},
+		{"(|x)|", "mismatched closing parentheses"},
+		{"|a || b|", "ambiguous absolute value bars"},
+		{"|a||b|", "ambiguous absolute value bars"},
+		{"mod(x)", "function 'mod' takes 2 arguments, got 1"},
+		{"-", "operator has no operand"},
+		{"!", "operator has no operand"},
+		{"x<", "operator has no operands"},
+		{"()!", "operator has no operand"},
+		{"mod 3", "function 'mod' takes 2 arguments, got 1"},
 	}
 
 	for _, test := range tests {
@@ -69,3 +114,38 @@ func TestParseErr(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		in   string
+		errs []string
+		pos  []int
+	}{
+		{"a*x+pi", nil, nil},
+		{"2b+sin(x)", []string{"unknown variable 'b'"}, []int{1}},
+		{"y + e^y", []string{"unknown variable 'y'", "unknown variable 'y'"}, []int{0, 6}},
+	}
+
+	opts := &ParseOptions{RejectUnknown: true, Known: []string{"x", "a"}}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := ParseWithOptions(test.in, opts)
+			if len(errs) != len(test.errs) {
+				t.Fatal(errs, "!=", test.errs)
+			} else if len(errs) == 0 && f == nil {
+				t.Fatal("nil function")
+			}
+			for i, err := range errs {
+				if err.Error() != test.errs[i] {
+					t.Fatal(err, "!=", test.errs[i])
+				} else if err.(ParseError).Pos() != test.pos[i] {
+					t.Fatal(err.(ParseError).Pos(), "!=", test.pos[i])
+				}
+			}
+		})
+	}
+
+	if _, errs := ParseWithOptions("y", &ParseOptions{Known: []string{"x"}}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+}
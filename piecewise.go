@@ -0,0 +1,186 @@
+package formulae
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+)
+
+var errNoCondition = fmt.Errorf("none of the conditions of piecewise hold")
+
+// boolNumber returns 1 for true and 0 for false.
+func boolNumber(b bool) complex128 {
+	if b {
+		return 1.0
+	}
+	return 0.0
+}
+
+// Piecewise is a function defined by conditions and values, such as piecewise(x<0, -x, x^2) or x<0 ? -x : x^2. Its arguments alternate between a condition and its value, optionally followed by a value for when none of the conditions hold. A condition holds when it is non-zero, and only the value of the first condition that holds is evaluated.
+type Piecewise struct {
+	args []Node
+}
+
+// Args returns the arguments of the piecewise function, which alternate between a condition and its value, optionally followed by a value for when none of the conditions hold.
+func (n *Piecewise) Args() []Node {
+	return append([]Node{}, n.args...)
+}
+
+// otherwise returns the value for when none of the conditions hold, or nil.
+func (n *Piecewise) otherwise() Node {
+	if len(n.args)%2 == 1 {
+		return n.args[len(n.args)-1]
+	}
+	return nil
+}
+
+func (n *Piecewise) String() string {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("piecewise(%v)", strings.Join(args, ","))
+}
+
+func (n *Piecewise) LaTeX() string {
+	sb := strings.Builder{}
+	sb.WriteString("\\begin{cases}")
+	for i := 0; i+1 < len(n.args); i += 2 {
+		if i != 0 {
+			sb.WriteString(" \\\\")
+		}
+		fmt.Fprintf(&sb, " %s & \\text{if } %s", n.args[i+1].LaTeX(), n.args[i].LaTeX())
+	}
+	if otherwise := n.otherwise(); otherwise != nil {
+		fmt.Fprintf(&sb, " \\\\ %s & \\text{otherwise}", otherwise.LaTeX())
+	}
+	sb.WriteString(" \\end{cases}")
+	return sb.String()
+}
+
+func (n *Piecewise) Equal(iother Node) bool {
+	other, ok := iother.(*Piecewise)
+	if !ok || len(n.args) != len(other.args) {
+		return false
+	}
+	for i := range n.args {
+		if !n.args[i].Equal(other.args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// boundaries returns the conditions for which cond changes its truth value, which is where both sides of a comparison are equal.
+func boundaries(in Node, bs []Node) []Node {
+	switch n := in.(type) {
+	case *Expr:
+		switch n.op {
+		case LessOp, LessEqualOp, GreaterOp, GreaterEqualOp, EqualOp, NotEqualOp:
+			return append(bs, &Expr{op: EqualOp, l: n.l, r: n.r})
+		case AndOp, OrOp:
+			return boundaries(n.r, boundaries(n.l, bs))
+		}
+	case *UnaryExpr:
+		if n.op == NotOp {
+			return boundaries(n.a, bs)
+		}
+	}
+	return append(bs, &Expr{op: EqualOp, l: in, r: ZeroNode})
+}
+
+// Derivative returns the piecewise derivative of each value. The derivative is undefined, that is NaN, at the boundaries of conditions that depend on x.
+func (n *Piecewise) Derivative(x string) Node {
+	var bs []Node
+	for i := 0; i+1 < len(n.args); i += 2 {
+		if dependsOn(n.args[i], x) {
+			bs = boundaries(n.args[i], bs)
+		}
+	}
+
+	args := []Node{}
+	if 0 < len(bs) {
+		args = append(args, joinOperands(OrOp, bs), &Number{val: complex(math.NaN(), 0.0)})
+	}
+	for i := 0; i+1 < len(n.args); i += 2 {
+		args = append(args, n.args[i], n.args[i+1].Derivative(x))
+	}
+	if otherwise := n.otherwise(); otherwise != nil {
+		args = append(args, otherwise.Derivative(x))
+	}
+	return &Piecewise{args: args}
+}
+
+// optimize removes conditions that are always false, replaces a condition that is always true and the ones that follow it by its value, and merges a nested piecewise function for when none of the conditions hold.
+func (n *Piecewise) optimize() Node {
+	args := []Node{}
+	for i := 0; i < len(n.args); i += 2 {
+		if i+1 == len(n.args) {
+			otherwise := Optimize(n.args[i])
+			if otherwisePiecewise, ok := otherwise.(*Piecewise); ok {
+				args = append(args, otherwisePiecewise.args...)
+			} else {
+				args = append(args, otherwise)
+			}
+			break
+		}
+
+		cond := Optimize(n.args[i])
+		if condNumber, ok := cond.(*Number); ok {
+			if condNumber.val != 0.0 {
+				args = append(args, Optimize(n.args[i+1]))
+				break
+			}
+			continue
+		}
+		args = append(args, cond, Optimize(n.args[i+1]))
+	}
+
+	if len(args) == 0 {
+		return n // none of the conditions hold
+	} else if len(args)%2 == 1 {
+		// all values are equal
+		same := true
+		for i := 1; i < len(args); i += 2 {
+			if !args[i].Equal(args[len(args)-1]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return args[len(args)-1]
+		}
+	}
+	return &Piecewise{args: args}
+}
+
+func (n *Piecewise) Calc(vars Vars) (complex128, error) {
+	for i := 0; i+1 < len(n.args); i += 2 {
+		cond, err := n.args[i].Calc(vars)
+		if err != nil {
+			return cmplx.NaN(), err
+		} else if cond != 0.0 {
+			return n.args[i+1].Calc(vars)
+		}
+	}
+	if otherwise := n.otherwise(); otherwise != nil {
+		return otherwise.Calc(vars)
+	}
+	return cmplx.NaN(), errNoCondition
+}
+
+func (n *Piecewise) CalcReal(vars Vars) (float64, error) {
+	for i := 0; i+1 < len(n.args); i += 2 {
+		cond, err := n.args[i].CalcReal(vars)
+		if err != nil {
+			return math.NaN(), err
+		} else if cond != 0.0 {
+			return n.args[i+1].CalcReal(vars)
+		}
+	}
+	if otherwise := n.otherwise(); otherwise != nil {
+		return otherwise.CalcReal(vars)
+	}
+	return math.NaN(), errNoCondition
+}
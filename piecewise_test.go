@@ -0,0 +1,235 @@
+package formulae
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePiecewise(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x < 0 ? -x : x^2", "piecewise(x<0,-x,x^2)"},
+		{"piecewise(x<0, -x, x^2)", "piecewise(x<0,-x,x^2)"},
+		{"piecewise(x<0, -x, x<=1, x^2)", "piecewise(x<0,-x,x<=1,x^2)"},
+		{"a ? b : c ? d : e", "piecewise(a,b,piecewise(c,d,e))"},
+		{"a ? b ? c : d : e", "piecewise(a,piecewise(b,c,d),e)"},
+		{"x+1 > 2*y ? 1 : 0", "piecewise(x+1>2*y,1,0)"},
+		{"x<=1 && !(y>2) || z!=3", "x<=1&&!(y>2)||z!=3"},
+		{"x>=1 == (y<2)", "x>=1==(y<2)"},
+		{"2x<3y", "2*x<3*y"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			} else if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestParsePiecewiseErr(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{"x ? 1", "conditional has no colon"},
+		{"x : 1", "unexpected colon"},
+		{"(x ? 1) : 2", "unexpected colon"},
+		{"piecewise(x)", "function 'piecewise' takes at least 2 arguments, got 1"},
+		{"x = 1", "bad input"},
+		{"x & y", "bad input"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			_, errs := Parse(test.in)
+			if len(errs) == 0 {
+				t.Fatal("nil !=", test.err)
+			} else if errs[0].Error() != test.err {
+				t.Fatal(errs[0].Error(), "!=", test.err)
+			}
+		})
+	}
+}
+
+func TestCalcPiecewise(t *testing.T) {
+	tests := []struct {
+		in string
+		x  float64
+		y  float64
+	}{
+		{"x < 0 ? -x : x^2", -2.0, 2.0},
+		{"x < 0 ? -x : x^2", 3.0, 9.0},
+		{"piecewise(x<0, -1, x<1, 0, 1)", 0.5, 0.0},
+		{"piecewise(x<0, -1, x<1, 0, 1)", 1.0, 1.0},
+		{"x == 0 ? 1 : sin(x)/x", 0.0, 1.0},
+		{"x != 0 && x > -1", 0.5, 1.0},
+		{"x <= 0 || x >= 1", 0.5, 0.0},
+		{"!(x > 0)", 0.5, 0.0},
+		{"!x", 0.0, 1.0},
+		{"(x < 1) + (x < 2)", 1.5, 1.0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			y, err := f.Calc(complex(test.x, 0.0))
+			if err != nil {
+				t.Fatal(err)
+			} else if y != complex(test.y, 0.0) {
+				t.Fatal(y, "!=", test.y)
+			}
+
+			yr, err := f.CalcReal(test.x)
+			if err != nil {
+				t.Fatal(err)
+			} else if yr != test.y {
+				t.Fatal(yr, "!=", test.y)
+			}
+
+			yd, err := f.DAG().Calc(complex(test.x, 0.0))
+			if err != nil {
+				t.Fatal(err)
+			} else if yd != complex(test.y, 0.0) {
+				t.Fatal(yd, "!=", test.y)
+			}
+
+			p, err := f.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			yp, err := p.Call(complex(test.x, 0.0))
+			if err != nil {
+				t.Fatal(err)
+			} else if yp != complex(test.y, 0.0) {
+				t.Fatal(yp, "!=", test.y)
+			}
+		})
+	}
+}
+
+func TestCalcPiecewiseErr(t *testing.T) {
+	tests := []struct {
+		in  string
+		x   complex128
+		err string
+	}{
+		{"piecewise(x<0, -x)", 1.0, "none of the conditions of piecewise hold"},
+		{"x < 0 ? 1/x : 1/(x-1)", 1.0, "division by zero"},
+		{"x < 0", 1i, "cannot compare complex numbers"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if _, err := f.Calc(test.x); err == nil || err.Error() != test.err {
+				t.Fatal(err, "!=", test.err)
+			} else if _, err := f.DAG().Calc(test.x); err == nil || err.Error() != test.err {
+				t.Fatal(err, "!=", test.err)
+			}
+			p, err := f.Compile()
+			if err != nil {
+				t.Fatal(err)
+			} else if _, err := p.Call(test.x); err == nil || err.Error() != test.err {
+				t.Fatal(err, "!=", test.err)
+			}
+		})
+	}
+}
+
+func TestDerivativePiecewise(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x < 0 ? -x : x^2", "piecewise(x==0,NaN,x<0,-1,2*x)"},
+		{"piecewise(x<0, -x, x<1, x^2, 1)", "piecewise(x==0||x==1,NaN,x<0,-1,x<1,2*x,0)"},
+		{"a > 0 ? x^2 : x", "piecewise(a>0,2*x,1)"},
+		{"a > 0 ? 1 : 2", "0"},
+		{"x > 1", "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			df := f.Derivative()
+			if df.String() != test.out {
+				t.Fatal(df, "!=", test.out)
+			}
+		})
+	}
+
+	f, errs := Parse("x < 0 ? -x : x^2")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if y, err := f.Derivative().CalcReal(0.0); err != nil || !math.IsNaN(y) {
+		t.Fatal(y, err, "!=", "NaN")
+	}
+}
+
+func TestOptimizePiecewise(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"1 < 2 ? x : y", "x"},
+		{"1 > 2 ? x : y", "y"},
+		{"piecewise(0, x, a, y, 1, z, w)", "piecewise(a,y,z)"},
+		{"a ? b : c ? d : e", "piecewise(a,b,c,d,e)"},
+		{"a ? x : x", "x"},
+		{"2 <= 1 || !0", "1"},
+		{"piecewise(0, x)", "piecewise(0,x)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			f.Optimize()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestLaTeXPiecewise(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x < 0 ? -x : x^2", "\\begin{cases} -x & \\text{if } x < 0 \\\\ x^{2} & \\text{otherwise} \\end{cases}"},
+		{"piecewise(x<=0, 0, x>=1, 1)", "\\begin{cases} 0 & \\text{if } x \\leq 0 \\\\ 1 & \\text{if } x \\geq 1 \\end{cases}"},
+		{"x == 0 && y != 1 || !z", "x = 0 \\land y \\neq 1 \\lor \\lnot z"},
+		{"piecewise(x<0, -1, x>0, 1, 0)", "\\begin{cases} -1 & \\text{if } x < 0 \\\\ 1 & \\text{if } x > 0 \\\\ 0 & \\text{otherwise} \\end{cases}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			} else if f.root.LaTeX() != test.out {
+				t.Fatal(f.root.LaTeX(), "!=", test.out)
+			}
+		})
+	}
+}
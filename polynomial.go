@@ -0,0 +1,358 @@
+package formulae
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxExpandExponent limits the integer powers of sums that are expanded.
+const maxExpandExponent = 64
+
+// factor is an atom raised to an integer power. An atom is a variable or a subexpression that is not polynomial, such as a function or a non-integer power.
+type factor struct {
+	key  string
+	atom Node
+	exp  int
+}
+
+// monomial is a coefficient times a product of factors sorted by key.
+type monomial struct {
+	coef    complex128
+	factors []factor
+}
+
+func (m monomial) key() string {
+	sb := strings.Builder{}
+	for i, f := range m.factors {
+		if i != 0 {
+			sb.WriteByte('*')
+		}
+		fmt.Fprintf(&sb, "(%s)^%d", f.key, f.exp)
+	}
+	return sb.String()
+}
+
+func (m monomial) degree() int {
+	deg := 0
+	for _, f := range m.factors {
+		deg += f.exp
+	}
+	return deg
+}
+
+func (m monomial) mul(o monomial) monomial {
+	r := monomial{coef: m.coef * o.coef}
+	i, j := 0, 0
+	for i < len(m.factors) || j < len(o.factors) {
+		if j == len(o.factors) || i < len(m.factors) && m.factors[i].key < o.factors[j].key {
+			r.factors = append(r.factors, m.factors[i])
+			i++
+		} else if i == len(m.factors) || o.factors[j].key < m.factors[i].key {
+			r.factors = append(r.factors, o.factors[j])
+			j++
+		} else {
+			if exp := m.factors[i].exp + o.factors[j].exp; exp != 0 {
+				r.factors = append(r.factors, factor{m.factors[i].key, m.factors[i].atom, exp})
+			}
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+func (m monomial) pow(k int) monomial {
+	r := monomial{coef: 1.0}
+	if 0 < k {
+		r.coef = ipow(m.coef, k)
+	} else if k < 0 {
+		r.coef = 1.0 / ipow(m.coef, -k)
+	}
+	if k != 0 {
+		for _, f := range m.factors {
+			r.factors = append(r.factors, factor{f.key, f.atom, f.exp * k})
+		}
+	}
+	return r
+}
+
+// node returns the product of the factors with positive and with negative exponents, which are nil if there are none.
+func (m monomial) node() (Node, Node) {
+	var num, den Node
+	for _, f := range m.factors {
+		exp := f.exp
+		if exp < 0 {
+			exp = -exp
+		}
+		n := f.atom
+		if 1 < exp {
+			n = &Expr{op: PowerOp, l: n, r: &Number{val: complex(float64(exp), 0.0)}}
+		}
+		if 0 < f.exp {
+			if num == nil {
+				num = n
+			} else {
+				num = &Expr{op: MultiplyOp, l: num, r: n}
+			}
+		} else if den == nil {
+			den = n
+		} else {
+			den = &Expr{op: MultiplyOp, l: den, r: n}
+		}
+	}
+	return num, den
+}
+
+// polynomial is a sum of monomials in canonical order: descending degree and then ascending keys of the factors. Like terms are combined and terms with a zero coefficient are removed.
+type polynomial []monomial
+
+func constPolynomial(c complex128) polynomial {
+	if c == 0.0 {
+		return polynomial{}
+	}
+	return polynomial{{coef: c}}
+}
+
+func atomPolynomial(n Node) polynomial {
+	return polynomial{{coef: 1.0, factors: []factor{{n.String(), n, 1}}}}
+}
+
+// normalize combines like terms and sorts the terms in canonical order.
+func (p polynomial) normalize() polynomial {
+	index := map[string]int{}
+	r := polynomial{}
+	for _, m := range p {
+		key := m.key()
+		if i, ok := index[key]; ok {
+			r[i].coef += m.coef
+		} else {
+			index[key] = len(r)
+			r = append(r, m)
+		}
+	}
+
+	q := r[:0]
+	for _, m := range r {
+		if m.coef != 0.0 {
+			q = append(q, m)
+		}
+	}
+	sort.SliceStable(q, func(i, j int) bool {
+		if di, dj := q[i].degree(), q[j].degree(); di != dj {
+			return di > dj
+		}
+		fi, fj := q[i].factors, q[j].factors
+		for k := 0; k < len(fi) && k < len(fj); k++ {
+			if fi[k].key != fj[k].key {
+				return fi[k].key < fj[k].key
+			} else if fi[k].exp != fj[k].exp {
+				return fi[k].exp > fj[k].exp
+			}
+		}
+		return len(fi) < len(fj)
+	})
+	return q
+}
+
+func (p polynomial) add(q polynomial) polynomial {
+	return append(append(polynomial{}, p...), q...).normalize()
+}
+
+func (p polynomial) scale(c complex128) polynomial {
+	r := make(polynomial, len(p))
+	for i, m := range p {
+		r[i] = monomial{coef: m.coef * c, factors: m.factors}
+	}
+	return r.normalize()
+}
+
+func (p polynomial) mul(q polynomial) polynomial {
+	r := make(polynomial, 0, len(p)*len(q))
+	for _, a := range p {
+		for _, b := range q {
+			r = append(r, a.mul(b))
+		}
+	}
+	return r.normalize()
+}
+
+// group returns p as a single atom if it has more than one term, so that it is not distributed over.
+func (p polynomial) group() polynomial {
+	if len(p) < 2 {
+		return p
+	}
+	return atomPolynomial(p.node())
+}
+
+// pow raises p to an integer power, expanding sums only if expand is set.
+func (p polynomial) pow(k int, expand bool) polynomial {
+	if len(p) == 1 {
+		return polynomial{p[0].pow(k)}.normalize()
+	} else if k == 0 {
+		return constPolynomial(1.0)
+	} else if len(p) == 0 && 0 < k {
+		return p
+	} else if !expand || k < 0 || maxExpandExponent < k {
+		return polynomial{atomPolynomial(p.node())[0].pow(k)}
+	}
+	r := p
+	for i := 1; i < k; i++ {
+		r = r.mul(p)
+	}
+	return r
+}
+
+// node returns the expression tree of p.
+func (p polynomial) node() Node {
+	var root Node
+	for _, m := range p {
+		c := m.coef
+		neg := root != nil && imag(c) == 0.0 && real(c) < 0.0
+		if neg {
+			c = -c
+		}
+
+		num, den := m.node()
+		if num == nil {
+			num = &Number{val: c}
+		} else if c == -1.0 {
+			num = negateNode(num)
+		} else if c != 1.0 {
+			num = &Expr{op: MultiplyOp, l: &Number{val: c}, r: num}
+		}
+		term := num
+		if den != nil {
+			term = &Expr{op: DivideOp, l: num, r: den}
+		}
+
+		if root == nil {
+			root = term
+		} else if neg {
+			root = &Expr{op: SubtractOp, l: root, r: term}
+		} else {
+			root = &Expr{op: AddOp, l: root, r: term}
+		}
+	}
+	if root == nil {
+		return ZeroNode
+	}
+	return root
+}
+
+// integer returns the value of p if it is a real integer constant.
+func (p polynomial) integer() (int, bool) {
+	if len(p) == 0 {
+		return 0, true
+	} else if len(p) == 1 && len(p[0].factors) == 0 && imag(p[0].coef) == 0.0 {
+		if y := real(p[0].coef); y == float64(int(y)) {
+			return int(y), true
+		}
+	}
+	return 0, false
+}
+
+// toPolynomial converts a node into a polynomial over its atoms. If expand is set, products and integer powers of sums are expanded, otherwise they are kept as atoms.
+func toPolynomial(in Node, expand bool) polynomial {
+	switch n := in.(type) {
+	case *Number:
+		return constPolynomial(n.val)
+	case *UnaryExpr:
+		if n.op == MinusOp {
+			return toPolynomial(n.a, expand).scale(-1.0)
+		}
+	case *Expr:
+		l := toPolynomial(n.l, expand)
+		r := toPolynomial(n.r, expand)
+		switch n.op {
+		case AddOp:
+			return l.add(r)
+		case SubtractOp:
+			return l.add(r.scale(-1.0))
+		case MultiplyOp:
+			if !expand {
+				l, r = l.group(), r.group()
+			}
+			return l.mul(r)
+		case DivideOp:
+			if !expand {
+				l = l.group()
+			}
+			return l.mul(r.pow(-1, expand))
+		case PowerOp:
+			if k, ok := r.integer(); ok {
+				return l.pow(k, expand)
+			}
+		}
+		return atomPolynomial(&Expr{op: n.op, l: l.node(), r: r.node()})
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = toPolynomial(arg, expand).node()
+		}
+		return atomPolynomial(&Func{name: n.name, args: args})
+	}
+	return atomPolynomial(in)
+}
+
+// Expand expands products and integer powers of sums in f, and collects like terms in canonical order.
+func (f *Function) Expand() {
+	f.root = toPolynomial(f.root, true).node()
+}
+
+// Collect collects like terms in f in canonical order, without expanding products and powers of sums.
+func (f *Function) Collect() {
+	f.root = toPolynomial(f.root, false).node()
+}
+
+// coefficients returns the expanded polynomial coefficients of f in the variable name, indexed by power.
+func (f *Function) coefficients(name string) ([]polynomial, error) {
+	coefs := []polynomial{}
+	for _, m := range toPolynomial(f.root, true) {
+		k := 0
+		c := monomial{coef: m.coef}
+		for _, fct := range m.factors {
+			if v, ok := fct.atom.(*Variable); ok && v.name == name {
+				k = fct.exp
+			} else if dependsOn(fct.atom, name) {
+				return nil, fmt.Errorf("'%v' is not a polynomial in %s", f.root, name)
+			} else {
+				c.factors = append(c.factors, fct)
+			}
+		}
+		if k < 0 {
+			return nil, fmt.Errorf("'%v' is not a polynomial in %s", f.root, name)
+		}
+		for len(coefs) <= k {
+			coefs = append(coefs, polynomial{})
+		}
+		coefs[k] = append(coefs[k], c)
+	}
+	return coefs, nil
+}
+
+// Coefficients returns the coefficients of f as a polynomial in the variable name, where the i-th coefficient belongs to name^i. The coefficients may depend on other variables. It returns an error if f is not a polynomial in name.
+func (f *Function) Coefficients(name string) ([]*Function, error) {
+	coefs, err := f.coefficients(name)
+	if err != nil {
+		return nil, err
+	}
+	fs := make([]*Function, len(coefs))
+	for i, c := range coefs {
+		fs[i] = &Function{
+			root:   c.normalize().node(),
+			Vars:   f.Vars,
+			params: f.params,
+		}
+	}
+	return fs, nil
+}
+
+// Degree returns the degree of f as a polynomial in the variable name, which is -1 for the zero polynomial. It returns an error if f is not a polynomial in name.
+func (f *Function) Degree(name string) (int, error) {
+	coefs, err := f.coefficients(name)
+	if err != nil {
+		return 0, err
+	}
+	return len(coefs) - 1, nil
+}
@@ -0,0 +1,133 @@
+package formulae
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x*x+2*x*x", "3*x^2"},
+		{"(x+1)^2", "x^2+2*x+1"},
+		{"(x+y)^3", "x^3+3*x^2*y+3*x*y^2+y^3"},
+		{"(x+1)*(x-1)", "x^2-1"},
+		{"(a-b)*(a+b)", "a^2-b^2"},
+		{"2*(x+1)+3*(x+1)", "5*x+5"},
+		{"3-x-x^2", "-(x^2)-x+3"},
+		{"6*x/(2*x)", "3"},
+		{"x^-2*x", "1/x"},
+		{"1/(x+1)+2/(1+x)", "3/(x+1)"},
+		{"sin(x+x)*sin(2x)", "sin(2*x)^2"},
+		{"x-x", "0"},
+		{"i*x*i", "-x"},
+		{"(x+1)^0", "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Expand()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"x*x+2*x*x", "3*x^2"},
+		{"(x+1)^2", "(x+1)^2"},
+		{"(x+1)*(x+1)", "(x+1)^2"},
+		{"2*(x+1)+3*(x+1)", "5*(x+1)"},
+		{"y*x+x*y", "2*x*y"},
+		{"1+x+x^2", "x^2+x+1"},
+		{"x+x", "2*x"},
+		{"sin(x)+sin(x)", "2*sin(x)"},
+		{"2*(x*3)", "6*x"},
+		{"(2*x)*3", "6*x"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			f.Collect()
+			if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestCoefficients(t *testing.T) {
+	tests := []struct {
+		in     string
+		degree int
+		coefs  []string
+	}{
+		{"(x+1)^2", 2, []string{"1", "2", "1"}},
+		{"(x+y)^2", 2, []string{"y^2", "2*y", "1"}},
+		{"x^3-x", 3, []string{"0", "-1", "0", "1"}},
+		{"sin(y)*x", 1, []string{"0", "sin(y)"}},
+		{"5", 0, []string{"5"}},
+		{"x-x", -1, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if degree, err := f.Degree("x"); err != nil {
+				t.Fatal(err)
+			} else if degree != test.degree {
+				t.Fatal(degree, "!=", test.degree)
+			}
+			coefs, err := f.Coefficients("x")
+			if err != nil {
+				t.Fatal(err)
+			} else if len(coefs) != len(test.coefs) {
+				t.Fatal(coefs, "!=", test.coefs)
+			}
+			for i := range coefs {
+				if coefs[i].String() != test.coefs[i] {
+					t.Fatal(coefs, "!=", test.coefs)
+				}
+			}
+		})
+	}
+}
+
+func TestCoefficientsErr(t *testing.T) {
+	tests := []string{
+		"1/x",
+		"sin(x)",
+		"x^0.5",
+		"e^x",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			f, errs := Parse(test)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if _, err := f.Coefficients("x"); err == nil {
+				t.Fatal("expected error")
+			}
+			if _, err := f.Degree("x"); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package formulae
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// IntegrateOptions are the options for numerical integration. A zero value for any field selects its default.
+type IntegrateOptions struct {
+	AbsTol   float64 // absolute tolerance, defaults to 1e-10
+	RelTol   float64 // relative tolerance, defaults to 1e-10
+	MaxEvals int     // maximum number of function evaluations, defaults to 100000
+}
+
+var DefaultIntegrateOptions = IntegrateOptions{
+	AbsTol:   1e-10,
+	RelTol:   1e-10,
+	MaxEvals: 100000,
+}
+
+// Gauss-Kronrod 7-15 nodes and weights on [-1,1], the Gauss nodes are the odd Kronrod nodes.
+var gkNodes = []float64{
+	0.991455371120812639206854697526329,
+	0.949107912342758524526189684047851,
+	0.864864423359769072789712788640926,
+	0.741531185599394439863864773280788,
+	0.586087235467691130294144845693013,
+	0.405845151377397166906606412076961,
+	0.207784955007898467600689403773245,
+	0.000000000000000000000000000000000,
+}
+
+var gkWeights = []float64{
+	0.022935322010529224963732008058970,
+	0.063092092629978553290700663189204,
+	0.104790010322250183839876322541518,
+	0.140653259715525918745189590510238,
+	0.169004726639267902826583426598550,
+	0.190350578064785409913256402421014,
+	0.204432940075298892414161999234649,
+	0.209482141084727828012999174891714,
+}
+
+var gaussWeights = []float64{
+	0.129484966168869693270611432679082,
+	0.279705391489276667901467771423780,
+	0.381830050505118944950369775488975,
+	0.417959183673469387755102040816327,
+}
+
+// Integrate numerically integrates f over x from a to b using adaptive Gauss-Kronrod quadrature, and returns the integral and an estimate of its absolute error. Either bound may be infinite, in which case the interval is transformed to a finite one. Integrable singularities at the bounds are allowed, since the bounds are never evaluated and a polynomial change of variables weakens the singularities. If opts is nil, DefaultIntegrateOptions is used.
+func (f *Function) Integrate(a, b float64, opts *IntegrateOptions) (complex128, float64, error) {
+	o := DefaultIntegrateOptions
+	if opts != nil {
+		if opts.AbsTol != 0.0 {
+			o.AbsTol = opts.AbsTol
+		}
+		if opts.RelTol != 0.0 {
+			o.RelTol = opts.RelTol
+		}
+		if opts.MaxEvals != 0 {
+			o.MaxEvals = opts.MaxEvals
+		}
+	}
+
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return cmplx.NaN(), math.NaN(), fmt.Errorf("integration bound is NaN")
+	} else if a == b {
+		return 0.0, 0.0, nil
+	} else if b < a {
+		y, errEst, err := f.Integrate(b, a, opts)
+		return -y, errEst, err
+	}
+
+	calc := func(x float64) (complex128, error) {
+		y, err := f.Calc(complex(x, 0.0))
+		if err == nil && (cmplx.IsNaN(y) || cmplx.IsInf(y)) {
+			err = fmt.Errorf("integrand is not finite at x=%v", x)
+		}
+		return y, err
+	}
+
+	// transform infinite bounds to a finite interval
+	g := calc
+	if math.IsInf(a, -1) && math.IsInf(b, 1) {
+		a, b = -1.0, 1.0
+		g = func(t float64) (complex128, error) {
+			// x = t/(1-t^2), dx = (1+t^2)/(1-t^2)^2 dt
+			u := 1.0 - t*t
+			y, err := calc(t / u)
+			return y * complex((1.0+t*t)/(u*u), 0.0), err
+		}
+	} else if math.IsInf(b, 1) {
+		x0 := a
+		a, b = 0.0, 1.0
+		g = func(t float64) (complex128, error) {
+			// x = a + t/(1-t), dx = 1/(1-t)^2 dt
+			u := 1.0 - t
+			y, err := calc(x0 + t/u)
+			return y * complex(1.0/(u*u), 0.0), err
+		}
+	} else if math.IsInf(a, -1) {
+		x0 := b
+		a, b = 0.0, 1.0
+		g = func(t float64) (complex128, error) {
+			// x = b - (1-t)/t, dx = 1/t^2 dt
+			y, err := calc(x0 - (1.0-t)/t)
+			return y * complex(1.0/(t*t), 0.0), err
+		}
+	}
+
+	// weaken singularities at the bounds by x = a + (b-a)*u(s) with u(s) = 3s^2 - 2s^3, whose derivative vanishes at s = 0 and s = 1
+	h, x0, x1 := g, a, b
+	g = func(s float64) (complex128, error) {
+		var x float64
+		if s < 0.5 {
+			x = x0 + (x1-x0)*s*s*(3.0-2.0*s)
+		} else {
+			r := 1.0 - s
+			x = x1 - (x1-x0)*r*r*(3.0-2.0*r)
+		}
+		y, err := h(x)
+		return y * complex((x1-x0)*6.0*s*(1.0-s), 0.0), err
+	}
+	a, b = 0.0, 1.0
+
+	q := &quadIntervals{}
+	y, errEst, err := gaussKronrod(g, a, b)
+	if err != nil {
+		return cmplx.NaN(), math.NaN(), err
+	}
+	heap.Push(q, quadInterval{a, b, y, errEst})
+	evals := 15
+	for o.AbsTol < errEst && o.RelTol*cmplx.Abs(y) < errEst {
+		if o.MaxEvals < evals+30 {
+			return y, errEst, fmt.Errorf("integral did not converge within %d evaluations", o.MaxEvals)
+		}
+
+		iv := heap.Pop(q).(quadInterval)
+		mid := iv.a + (iv.b-iv.a)/2.0
+		if mid <= iv.a || iv.b <= mid {
+			return y, errEst, fmt.Errorf("integral did not converge, interval too small")
+		}
+		y1, err1, err := gaussKronrod(g, iv.a, mid)
+		if err != nil {
+			return cmplx.NaN(), math.NaN(), err
+		}
+		y2, err2, err := gaussKronrod(g, mid, iv.b)
+		if err != nil {
+			return cmplx.NaN(), math.NaN(), err
+		}
+		evals += 30
+		heap.Push(q, quadInterval{iv.a, mid, y1, err1})
+		heap.Push(q, quadInterval{mid, iv.b, y2, err2})
+		y += y1 + y2 - iv.y
+		errEst += err1 + err2 - iv.err
+	}
+
+	// sum again to avoid accumulated rounding errors
+	y, errEst = 0.0, 0.0
+	for _, iv := range *q {
+		y += iv.y
+		errEst += iv.err
+	}
+	return y, errEst, nil
+}
+
+// gaussKronrod returns the 15-point Kronrod estimate of the integral of g over [a,b] and the difference with the embedded 7-point Gauss estimate as the error.
+func gaussKronrod(g func(float64) (complex128, error), a, b float64) (complex128, float64, error) {
+	c := (a + b) / 2.0
+	h := (b - a) / 2.0
+	var kronrod, gauss complex128
+	for i, node := range gkNodes {
+		xs := []float64{c - h*node, c + h*node}
+		if node == 0.0 {
+			xs = xs[:1]
+		}
+		for _, x := range xs {
+			y, err := g(x)
+			if err != nil {
+				return cmplx.NaN(), math.NaN(), err
+			}
+			kronrod += complex(gkWeights[i], 0.0) * y
+			if i%2 == 1 {
+				gauss += complex(gaussWeights[i/2], 0.0) * y
+			}
+		}
+	}
+	kronrod *= complex(h, 0.0)
+	gauss *= complex(h, 0.0)
+	return kronrod, cmplx.Abs(kronrod - gauss), nil
+}
+
+type quadInterval struct {
+	a, b float64
+	y    complex128
+	err  float64
+}
+
+// quadIntervals is a max-heap of intervals ordered by their error estimate.
+type quadIntervals []quadInterval
+
+func (q quadIntervals) Len() int            { return len(q) }
+func (q quadIntervals) Less(i, j int) bool  { return q[i].err > q[j].err }
+func (q quadIntervals) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *quadIntervals) Push(x interface{}) { *q = append(*q, x.(quadInterval)) }
+func (q *quadIntervals) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,71 @@
+package formulae
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		in   string
+		a, b float64
+		out  complex128
+	}{
+		{"x^2", 0, 3, 9},
+		{"x^2", 3, 0, -9},
+		{"x^2", 1, 1, 0},
+		{"sin(x)", 0, math.Pi, 2},
+		{"e^x", 0, 1, math.E - 1},
+		{"1i*x", 0, 2, 2i},
+		{"1/sqrt(x)", 0, 1, 2},
+		{"ln(x)", 0, 1, -1},
+		{"1/sqrt(1-x^2)", -1, 1, math.Pi},
+		{"e^-x", 0, math.Inf(1), 1},
+		{"1/(1+x^2)", math.Inf(-1), 0, math.Pi / 2},
+		{"e^-(x^2)", math.Inf(-1), math.Inf(1), complex(math.Sqrt(math.Pi), 0)},
+		{"1/x^2", 1, math.Inf(1), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			y, errEst, err := f.Integrate(test.a, test.b, nil)
+			if err != nil {
+				t.Fatal(err)
+			} else if 1e-6 < cmplx.Abs(y-test.out) {
+				t.Fatal(y, "!=", test.out)
+			} else if 1e-6 < errEst {
+				t.Fatal("error estimate", errEst, "too large")
+			}
+		})
+	}
+}
+
+func TestIntegrateErr(t *testing.T) {
+	tests := []struct {
+		in   string
+		a, b float64
+		opts *IntegrateOptions
+	}{
+		{"1/x", -1, 1, nil},
+		{"sin(1/x)", 0, 1, &IntegrateOptions{MaxEvals: 1000}},
+		{"y", 0, 1, nil},
+		{"x", math.NaN(), 1, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if _, _, err := f.Integrate(test.a, test.b, test.opts); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package formulae
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// RootOptions are the options for root finding. A zero value for any field selects its default.
+type RootOptions struct {
+	Tol     float64 // absolute tolerance of a root, defaults to 1e-12
+	MaxIter int     // maximum number of iterations per root, defaults to 100
+	Samples int     // number of subintervals that are scanned for roots, defaults to 1000
+}
+
+var DefaultRootOptions = RootOptions{
+	Tol:     1e-12,
+	MaxIter: 100,
+	Samples: 1000,
+}
+
+func (o *RootOptions) withDefaults() RootOptions {
+	r := DefaultRootOptions
+	if o != nil {
+		if o.Tol != 0.0 {
+			r.Tol = o.Tol
+		}
+		if o.MaxIter != 0 {
+			r.MaxIter = o.MaxIter
+		}
+		if o.Samples != 0 {
+			r.Samples = o.Samples
+		}
+	}
+	return r
+}
+
+// Roots returns the real roots of f in the interval [a,b] in increasing order. The interval is sampled for sign changes, each of which is refined by Brent's method accelerated by Newton steps using the derivative of f. Roots without a sign change, such as double roots, are found by Newton's method from local minima of |f|. Points where f is not real are skipped. If opts is nil, DefaultRootOptions is used.
+func (f *Function) Roots(a, b float64, opts *RootOptions) ([]float64, error) {
+	o := opts.withDefaults()
+	if math.IsNaN(a) || math.IsNaN(b) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return nil, fmt.Errorf("interval bounds must be finite")
+	} else if b < a {
+		a, b = b, a
+	}
+	df := f.Derivative()
+
+	n := o.Samples
+	xs := make([]float64, n+1)
+	ys := make([]float64, n+1)
+	for i := range xs {
+		xs[i] = a + (b-a)*float64(i)/float64(n)
+		if y, err := f.CalcReal(xs[i]); err == nil && !math.IsInf(y, 0) {
+			ys[i] = y
+		} else {
+			ys[i] = math.NaN()
+		}
+	}
+
+	roots := []float64{}
+	for i := range xs {
+		if ys[i] == 0.0 {
+			roots = append(roots, xs[i])
+			continue
+		} else if i == n || math.IsNaN(ys[i]) || math.IsNaN(ys[i+1]) {
+			continue
+		}
+
+		if ys[i+1] != 0.0 && math.Signbit(ys[i]) != math.Signbit(ys[i+1]) {
+			x, err := f.brent(df, xs[i], xs[i+1], ys[i], ys[i+1], o)
+			if err != nil {
+				return roots, err
+			} else if y, err := f.CalcReal(x); err == nil && math.Abs(y) <= math.Abs(ys[i])+math.Abs(ys[i+1]) {
+				roots = append(roots, x) // reject poles such as that of 1/x
+			}
+		} else if 0 < i && math.Abs(ys[i]) < math.Abs(ys[i-1]) && math.Abs(ys[i]) <= math.Abs(ys[i+1]) {
+			// local minimum of |f| without a sign change
+			if x, err := f.newton(df, xs[i], xs[i-1], xs[i+1], o); err == nil {
+				roots = append(roots, x)
+			}
+		}
+	}
+
+	sort.Float64s(roots)
+	unique := roots[:0]
+	for _, x := range roots {
+		if len(unique) == 0 || o.Tol < x-unique[len(unique)-1] {
+			unique = append(unique, x)
+		}
+	}
+	return unique, nil
+}
+
+// brent returns the root of f in [a,b] where f(a) and f(b) have opposite signs. Newton steps are used when they fall within the bracket and converge fast enough, otherwise it falls back to inverse quadratic interpolation, the secant method, or bisection.
+func (f *Function) brent(df *Function, a, b, fa, fb float64, o RootOptions) (float64, error) {
+	if math.Abs(fa) < math.Abs(fb) {
+		a, b, fa, fb = b, a, fb, fa
+	}
+	c, fc := a, fa // previous iterate
+	d := b - a     // last step
+	e := d         // step before the last step
+	for i := 0; i < o.MaxIter; i++ {
+		m := (a - b) / 2.0
+		if fb == 0.0 || math.Abs(m) <= o.Tol {
+			return b, nil
+		}
+
+		step := math.NaN()
+		if dfb, err := df.CalcReal(b); err == nil && dfb != 0.0 {
+			step = -fb / dfb
+		}
+		if math.IsNaN(step) || step*m <= 0.0 || math.Abs(m) < math.Abs(step) || math.Abs(e)/2.0 <= math.Abs(step) {
+			if fc != fa && fc != fb {
+				// inverse quadratic interpolation
+				s := a*fb*fc/((fa-fb)*(fa-fc)) + b*fa*fc/((fb-fa)*(fb-fc)) + c*fa*fb/((fc-fa)*(fc-fb))
+				step = s - b
+			} else {
+				// secant
+				step = -fb * (b - a) / (fb - fa)
+			}
+			if step*m <= 0.0 || 1.5*math.Abs(m) < math.Abs(step) || math.Abs(e)/2.0 <= math.Abs(step) {
+				step = m // bisection
+			}
+		}
+		if math.Abs(step) < o.Tol/2.0 {
+			step = math.Copysign(o.Tol/2.0, m)
+		}
+		e, d = d, step
+
+		c, fc = b, fb
+		b += step
+		y, err := f.CalcReal(b)
+		if err != nil {
+			return b, err
+		}
+		fb = y
+		if math.Signbit(fb) == math.Signbit(fa) {
+			a, fa = c, fc // the previous iterate and b bracket the root
+		}
+		if math.Abs(fa) < math.Abs(fb) {
+			a, b, fa, fb = b, a, fb, fa
+		}
+	}
+	return b, fmt.Errorf("root finding did not converge within %d iterations", o.MaxIter)
+}
+
+// newton returns the root found by Newton's method from x, and returns an error when it leaves the interval [a,b] or does not converge.
+func (f *Function) newton(df *Function, x, a, b float64, o RootOptions) (float64, error) {
+	for i := 0; i < o.MaxIter; i++ {
+		y, err := f.CalcReal(x)
+		if err != nil {
+			return x, err
+		} else if y == 0.0 {
+			return x, nil
+		}
+		dy, err := df.CalcReal(x)
+		if err != nil {
+			return x, err
+		} else if dy == 0.0 {
+			return x, fmt.Errorf("derivative is zero at %v", x)
+		}
+
+		step := y / dy
+		x -= step
+		if x < a || b < x {
+			return x, fmt.Errorf("root finding left the interval")
+		} else if math.Abs(step) <= o.Tol {
+			return x, nil
+		}
+	}
+	return x, fmt.Errorf("root finding did not converge within %d iterations", o.MaxIter)
+}
+
+// RootComplex returns a root of f in the complex plane using Newton's method starting from z. If opts is nil, DefaultRootOptions is used.
+func (f *Function) RootComplex(z complex128, opts *RootOptions) (complex128, error) {
+	o := opts.withDefaults()
+	df := f.Derivative()
+	for i := 0; i < o.MaxIter; i++ {
+		y, err := f.Calc(z)
+		if err != nil {
+			return z, err
+		} else if y == 0.0 {
+			return z, nil
+		}
+		dy, err := df.Calc(z)
+		if err != nil {
+			return z, err
+		} else if dy == 0.0 {
+			return z, fmt.Errorf("derivative is zero at %v", z)
+		}
+
+		step := y / dy
+		z -= step
+		if cmplx.IsNaN(z) || cmplx.IsInf(z) {
+			return z, fmt.Errorf("root finding diverged")
+		} else if cmplx.Abs(step) <= o.Tol {
+			return z, nil
+		}
+	}
+	return z, fmt.Errorf("root finding did not converge within %d iterations", o.MaxIter)
+}
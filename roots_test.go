@@ -0,0 +1,87 @@
+package formulae
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestRoots(t *testing.T) {
+	tests := []struct {
+		in    string
+		a, b  float64
+		roots []float64
+	}{
+		{"x^2-2", -2, 2, []float64{-math.Sqrt2, math.Sqrt2}},
+		{"x^2-2", 2, -2, []float64{-math.Sqrt2, math.Sqrt2}},
+		{"sin(x)", -1, 10, []float64{0, math.Pi, 2 * math.Pi, 3 * math.Pi}},
+		{"cos(x)-x", 0, 1, []float64{0.7390851332151607}},
+		{"(x-0.3)^2", -1, 1, []float64{0.3}},
+		{"x^3-x", -2, 2, []float64{-1, 0, 1}},
+		{"1/x", -1, 1, []float64{}},
+		{"x^2+1", -5, 5, []float64{}},
+		{"sqrt(x)-1", -4, 4, []float64{1}},
+		{"e^x-2", 0, 1, []float64{math.Ln2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			roots, err := f.Roots(test.a, test.b, nil)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(roots) != len(test.roots) {
+				t.Fatal(roots, "!=", test.roots)
+			}
+			for i := range roots {
+				if 1e-6 < math.Abs(roots[i]-test.roots[i]) {
+					t.Fatal(roots, "!=", test.roots)
+				}
+			}
+		})
+	}
+}
+
+func TestRootComplex(t *testing.T) {
+	tests := []struct {
+		in   string
+		z    complex128
+		root complex128
+	}{
+		{"x^2+1", 1 + 1i, 1i},
+		{"x^2+1", 1 - 1i, -1i},
+		{"x^3-1", -1 + 1i, complex(-0.5, math.Sqrt(3)/2)},
+		{"e^x+1", 3i, complex(0, math.Pi)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			root, err := f.RootComplex(test.z, nil)
+			if err != nil {
+				t.Fatal(err)
+			} else if 1e-6 < cmplx.Abs(root-test.root) {
+				t.Fatal(root, "!=", test.root)
+			}
+		})
+	}
+}
+
+func TestRootsErr(t *testing.T) {
+	f, _ := Parse("x^2+1")
+	if _, err := f.RootComplex(0, nil); err == nil {
+		t.Fatal("expected error for zero derivative")
+	}
+	if _, err := f.RootComplex(2, &RootOptions{MaxIter: 5}); err == nil {
+		t.Fatal("expected error for iteration limit")
+	}
+	if _, err := f.Roots(0, math.Inf(1), nil); err == nil {
+		t.Fatal("expected error for infinite interval")
+	}
+}
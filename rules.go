@@ -0,0 +1,281 @@
+package formulae
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a rewrite rule that replaces expressions matching its left-hand side by its right-hand side. Pattern variables such as ?a match any expression, and must match equivalent expressions where they occur more than once.
+type Rule struct {
+	lhs, rhs Node
+}
+
+// ParseRule parses a rule of the form lhs -> rhs, such as sin(?a)^2 + cos(?a)^2 -> 1, where both sides are formulas that may contain pattern variables. All pattern variables of the right-hand side must occur in the left-hand side.
+func ParseRule(in string) (*Rule, []error) {
+	i := strings.Index(in, "->")
+	if i == -1 {
+		return nil, []error{fmt.Errorf("rule has no '->'")}
+	}
+
+	lhs, errs := Parse(in[:i])
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	rhs, errs := Parse(in[i+2:])
+	if len(errs) != 0 {
+		for j, err := range errs {
+			if perr, ok := err.(ParseError); ok {
+				errs[j] = ParseErrorf(perr.Pos()+i+2, "%s", perr.msg)
+			}
+		}
+		return nil, errs
+	}
+
+	bound := map[string]bool{}
+	for _, name := range patternVariables(lhs.root, nil) {
+		bound[name] = true
+	}
+	for _, name := range patternVariables(rhs.root, nil) {
+		if !bound[name] {
+			return nil, []error{fmt.Errorf("pattern variable '%s' does not occur in the left-hand side", name)}
+		}
+	}
+	return &Rule{lhs.root, rhs.root}, nil
+}
+
+func (r *Rule) String() string {
+	return r.lhs.String() + " -> " + r.rhs.String()
+}
+
+func isPatternVariable(n Node) (string, bool) {
+	if nVariable, ok := n.(*Variable); ok && strings.HasPrefix(nVariable.name, "?") {
+		return nVariable.name, true
+	}
+	return "", false
+}
+
+func patternVariables(in Node, names []string) []string {
+	switch n := in.(type) {
+	case *Variable:
+		if name, ok := isPatternVariable(n); ok {
+			names = append(names, name)
+		}
+	case *Expr:
+		names = patternVariables(n.l, names)
+		names = patternVariables(n.r, names)
+	case *UnaryExpr:
+		names = patternVariables(n.a, names)
+	case *Func:
+		for _, arg := range n.args {
+			names = patternVariables(arg, names)
+		}
+	case *Piecewise:
+		for _, arg := range n.args {
+			names = patternVariables(arg, names)
+		}
+	}
+	return names
+}
+
+// bindings maps pattern variables to the expressions they matched.
+type bindings map[string]Node
+
+func (b bindings) copy() bindings {
+	c := make(bindings, len(b))
+	for name, n := range b {
+		c[name] = n
+	}
+	return c
+}
+
+// operands flattens a chain of the commutative operator op into a list of operands.
+func operands(in Node, op Operator, ns []Node) []Node {
+	if n, ok := in.(*Expr); ok && n.op == op {
+		ns = operands(n.l, op, ns)
+		return operands(n.r, op, ns)
+	}
+	return append(ns, in)
+}
+
+func joinOperands(op Operator, ns []Node) Node {
+	root := ns[0]
+	for _, n := range ns[1:] {
+		root = &Expr{op: op, l: root, r: n}
+	}
+	return root
+}
+
+// match returns whether n matches the pattern, and binds the pattern variables. Additions and multiplications match their operands in any order.
+func match(pattern, in Node, b bindings) bool {
+	if name, ok := isPatternVariable(pattern); ok {
+		if bound, ok := b[name]; ok {
+			return Equivalent(bound, in)
+		}
+		b[name] = in
+		return true
+	}
+
+	switch p := pattern.(type) {
+	case *Expr:
+		n, ok := in.(*Expr)
+		if !ok || p.op != n.op {
+			return false
+		} else if p.op == AddOp || p.op == MultiplyOp {
+			ps := operands(p, p.op, nil)
+			ns := operands(n, n.op, nil)
+			if len(ps) != len(ns) {
+				return false
+			}
+			rest, ok := matchOperands(ps, ns, b)
+			return ok && len(rest) == 0
+		}
+		return match(p.l, n.l, b) && match(p.r, n.r, b)
+	case *UnaryExpr:
+		n, ok := in.(*UnaryExpr)
+		return ok && p.op == n.op && match(p.a, n.a, b)
+	case *Func:
+		n, ok := in.(*Func)
+		if !ok || p.name != n.name || len(p.args) != len(n.args) {
+			return false
+		}
+		for i := range p.args {
+			if !match(p.args[i], n.args[i], b) {
+				return false
+			}
+		}
+		return true
+	case *Piecewise:
+		n, ok := in.(*Piecewise)
+		if !ok || len(p.args) != len(n.args) {
+			return false
+		}
+		for i := range p.args {
+			if !match(p.args[i], n.args[i], b) {
+				return false
+			}
+		}
+		return true
+	}
+	return pattern.Equal(in)
+}
+
+// matchOperands matches each pattern to a distinct operand, and returns the operands that were not matched.
+func matchOperands(ps, ns []Node, b bindings) ([]Node, bool) {
+	if len(ps) == 0 {
+		return ns, true
+	}
+	for i, n := range ns {
+		c := b.copy()
+		if match(ps[0], n, c) {
+			rest := append(append([]Node{}, ns[:i]...), ns[i+1:]...)
+			if rest, ok := matchOperands(ps[1:], rest, c); ok {
+				for name, n := range c {
+					b[name] = n
+				}
+				return rest, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// instantiate replaces the pattern variables in n by their bindings.
+func instantiate(in Node, b bindings) Node {
+	switch n := in.(type) {
+	case *Variable:
+		if name, ok := isPatternVariable(n); ok {
+			return b[name]
+		}
+		return &Variable{name: n.name, pos: -1} // the position refers to the rule, not to the formula
+	case *Expr:
+		return &Expr{op: n.op, l: instantiate(n.l, b), r: instantiate(n.r, b)}
+	case *UnaryExpr:
+		return &UnaryExpr{op: n.op, a: instantiate(n.a, b)}
+	case *Func:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = instantiate(arg, b)
+		}
+		return &Func{name: n.name, args: args}
+	case *Piecewise:
+		args := make([]Node, len(n.args))
+		for i, arg := range n.args {
+			args[i] = instantiate(arg, b)
+		}
+		return &Piecewise{args: args}
+	}
+	return in
+}
+
+// apply rewrites n if it matches the rule. A rule whose left-hand side is an addition or multiplication also matches a subset of the operands of a longer chain, in which case the remaining operands are kept.
+func (r *Rule) apply(in Node) (Node, bool) {
+	b := bindings{}
+	if p, ok := r.lhs.(*Expr); ok && (p.op == AddOp || p.op == MultiplyOp) {
+		if n, ok := in.(*Expr); ok && n.op == p.op {
+			ps := operands(p, p.op, nil)
+			ns := operands(n, n.op, nil)
+			if len(ps) <= len(ns) {
+				if rest, ok := matchOperands(ps, ns, b); ok {
+					return joinOperands(p.op, append(rest, instantiate(r.rhs, b))), true
+				}
+			}
+		}
+		return in, false
+	} else if match(r.lhs, in, b) {
+		return instantiate(r.rhs, b), true
+	}
+	return in, false
+}
+
+// rewrite applies the first matching rule to the innermost matching expression.
+func rewrite(in Node, rules []*Rule) (Node, bool) {
+	switch n := in.(type) {
+	case *Expr:
+		if l, ok := rewrite(n.l, rules); ok {
+			return &Expr{op: n.op, l: l, r: n.r}, true
+		} else if r, ok := rewrite(n.r, rules); ok {
+			return &Expr{op: n.op, l: n.l, r: r}, true
+		}
+	case *UnaryExpr:
+		if a, ok := rewrite(n.a, rules); ok {
+			return &UnaryExpr{op: n.op, a: a}, true
+		}
+	case *Func:
+		for i, arg := range n.args {
+			if a, ok := rewrite(arg, rules); ok {
+				args := append([]Node{}, n.args...)
+				args[i] = a
+				return &Func{name: n.name, args: args}, true
+			}
+		}
+	case *Piecewise:
+		for i, arg := range n.args {
+			if a, ok := rewrite(arg, rules); ok {
+				args := append([]Node{}, n.args...)
+				args[i] = a
+				return &Piecewise{args: args}, true
+			}
+		}
+	}
+	for _, rule := range rules {
+		if out, ok := rule.apply(in); ok {
+			return out, true
+		}
+	}
+	return in, false
+}
+
+// ApplyRules rewrites f with the rules until none of them match. Each step applies the first matching rule to the innermost matching expression. It returns an error if no fixpoint is reached within maxSteps steps, in which case f holds the result after maxSteps steps.
+func (f *Function) ApplyRules(rules []*Rule, maxSteps int) error {
+	for i := 0; i < maxSteps; i++ {
+		root, ok := rewrite(f.root, rules)
+		if !ok {
+			return nil
+		}
+		f.root = root
+	}
+	if _, ok := rewrite(f.root, rules); ok {
+		return fmt.Errorf("rules did not reach a fixpoint within %d steps", maxSteps)
+	}
+	return nil
+}
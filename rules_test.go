@@ -0,0 +1,94 @@
+package formulae
+
+import "testing"
+
+func TestApplyRules(t *testing.T) {
+	tests := []struct {
+		rules []string
+		in    string
+		out   string
+	}{
+		{[]string{"sin(?a)^2 + cos(?a)^2 -> 1"}, "sin(x)^2+cos(x)^2", "1"},
+		{[]string{"sin(?a)^2 + cos(?a)^2 -> 1"}, "cos(2*y)^2+sin(2*y)^2", "1"},
+		{[]string{"sin(?a)^2 + cos(?a)^2 -> 1"}, "3+sin(x)^2+y+cos(x)^2", "3+y+1"},
+		{[]string{"sin(?a)^2 + cos(?a)^2 -> 1"}, "sin(x)^2+cos(y)^2", "sin(x)^2+cos(y)^2"},
+		{[]string{"sin(?a)^2 + cos(?a)^2 -> 1"}, "e^(sin(x)^2+cos(x)^2)", "e^1"},
+		{[]string{"?a*?b + ?a*?c -> ?a*(?b+?c)"}, "x*y+z*x", "x*(y+z)"},
+		{[]string{"?a*1 -> ?a"}, "1*x*1", "x"},
+		{[]string{"log(?a) + log(?b) -> log(?a*?b)"}, "ln(x)+ln(y)+ln(z)", "log(x*y*z)"},
+		{[]string{"?a*?a -> ?a^2"}, "sin(x)*sin(x)*y", "sin(x)^2*y"},
+		{[]string{"?a - ?a -> 0", "?a + 0 -> ?a"}, "(x*y - x*y) + z", "z"},
+		{[]string{"-(-?a) -> ?a"}, "--x", "x"},
+		{[]string{"?a - ?a -> 0"}, "(x+y) - (y+x)", "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			rules := []*Rule{}
+			for _, s := range test.rules {
+				rule, errs := ParseRule(s)
+				if len(errs) != 0 {
+					t.Fatal(errs)
+				}
+				rules = append(rules, rule)
+			}
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if err := f.ApplyRules(rules, 100); err != nil {
+				t.Fatal(err)
+			} else if f.String() != test.out {
+				t.Fatal(f, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestApplyRulesPositions(t *testing.T) {
+	rule, _ := ParseRule("sin(?a) -> y*?a")
+	f, _ := Parse("sin(x)")
+	if err := f.ApplyRules([]*Rule{rule}, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	vs := f.Variables()
+	if len(vs) != 2 || vs[0].Name != "x" || vs[1].Name != "y" {
+		t.Fatal(vs)
+	} else if len(vs[0].Pos) != 1 || vs[0].Pos[0] != 4 {
+		t.Fatal(vs[0].Pos, "!=", []int{4})
+	} else if len(vs[1].Pos) != 0 {
+		t.Fatal(vs[1].Pos, "!=", []int{})
+	}
+}
+
+func TestApplyRulesLimit(t *testing.T) {
+	rule, _ := ParseRule("?a+?b -> ?b+?a")
+	f, _ := Parse("x+y")
+	if err := f.ApplyRules([]*Rule{rule}, 10); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, errs := ParseRule("sin(?a)^2 + cos(?a)^2 -> 1")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	} else if rule.String() != "sin(?a)^2+cos(?a)^2 -> 1" {
+		t.Fatal(rule, "!=", "sin(?a)^2+cos(?a)^2 -> 1")
+	}
+
+	tests := []string{
+		"sin(?a)",
+		"sin(?a) -> ?b",
+		"sin(?a) -> (",
+		"?a? -> 1",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			if _, errs := ParseRule(test); len(errs) == 0 {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
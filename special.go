@@ -0,0 +1,191 @@
+package formulae
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// cbrt returns the cube root of z. For real z it returns the real cube root, otherwise it returns the principal cube root.
+func cbrt(z complex128) complex128 {
+	if imag(z) == 0.0 {
+		return complex(math.Cbrt(real(z)), 0.0)
+	}
+	return cmplx.Pow(z, 1.0/3.0)
+}
+
+// log2 returns the binary logarithm of z.
+func log2(z complex128) complex128 {
+	if imag(z) == 0.0 && 0.0 < real(z) {
+		return complex(math.Log2(real(z)), 0.0)
+	}
+	return cmplx.Log(z) / math.Ln2
+}
+
+// erf returns the error function of z. Close to the origin it uses the Maclaurin series, while further away it uses the continued fraction of erfc.
+func erf(z complex128) complex128 {
+	if imag(z) == 0.0 {
+		return complex(math.Erf(real(z)), 0.0)
+	} else if real(z) < 0.0 {
+		return -erf(-z)
+	}
+
+	if 1.5 <= real(z) && 3.0 <= cmplx.Abs(z) {
+		// erfc(z) = exp(-z^2)/sqrt(pi) * 1/(z+ 1/2/(z+ 1/(z+ 3/2/(z+ ...))))
+		t := z
+		for k := 60; 0 < k; k-- {
+			t = z + complex(float64(k)/2.0, 0.0)/t
+		}
+		return 1.0 - cmplx.Exp(-z*z)/(complex(math.SqrtPi, 0.0)*t)
+	}
+
+	// erf(z) = 2/sqrt(pi) * sum (-1)^n z^(2n+1) / (n! (2n+1))
+	z2 := -z * z
+	term := z
+	sum := z
+	for n := 1; n < 200; n++ {
+		term *= z2 / complex(float64(n), 0.0)
+		d := term / complex(float64(2*n+1), 0.0)
+		sum += d
+		if cmplx.Abs(d) < 1e-17*cmplx.Abs(sum) {
+			break
+		}
+	}
+	return complex(2.0/math.SqrtPi, 0.0) * sum
+}
+
+var lanczos = []float64{
+	0.99999999999980993,
+	676.5203681218851,
+	-1259.1392167224028,
+	771.32342877765313,
+	-176.61502916214059,
+	12.507343278686905,
+	-0.13857109526572012,
+	9.9843695780195716e-6,
+	1.5056327351493116e-7,
+}
+
+// gamma returns the gamma function of z using the Lanczos approximation and the reflection formula.
+func gamma(z complex128) complex128 {
+	if imag(z) == 0.0 {
+		return complex(math.Gamma(real(z)), 0.0)
+	} else if real(z) < 0.5 {
+		return complex(math.Pi, 0.0) / (cmplx.Sin(math.Pi*z) * gamma(1.0-z))
+	}
+
+	z -= 1.0
+	x := complex(lanczos[0], 0.0)
+	for i := 1; i < len(lanczos); i++ {
+		x += complex(lanczos[i], 0.0) / (z + complex(float64(i), 0.0))
+	}
+	t := z + 7.5
+	return complex(math.Sqrt(2.0*math.Pi), 0.0) * cmplx.Pow(t, z+0.5) * cmplx.Exp(-t) * x
+}
+
+// bernoulli holds the Bernoulli numbers B_2k for k = 1..7.
+var bernoulli = []float64{1.0 / 6.0, -1.0 / 30.0, 1.0 / 42.0, -1.0 / 30.0, 5.0 / 66.0, -691.0 / 2730.0, 7.0 / 6.0}
+
+// digamma returns the logarithmic derivative of the gamma function of z.
+func digamma(z complex128) complex128 {
+	if imag(z) == 0.0 && real(z) <= 0.0 && real(z) == math.Trunc(real(z)) {
+		return cmplx.NaN()
+	} else if real(z) < 0.0 {
+		// reflection: psi(z) = psi(1-z) - pi/tan(pi*z)
+		return digamma(1.0-z) - complex(math.Pi, 0.0)/cmplx.Tan(math.Pi*z)
+	}
+
+	// recurrence: psi(z) = psi(z+1) - 1/z
+	var y complex128
+	for real(z) < 10.0 {
+		y -= 1.0 / z
+		z += 1.0
+	}
+
+	// asymptotic expansion: psi(z) ~ log(z) - 1/(2z) - sum B_2k / (2k z^2k)
+	y += cmplx.Log(z) - 0.5/z
+	z2 := 1.0 / (z * z)
+	zk := z2
+	for k, b := range bernoulli {
+		y -= complex(b/float64(2*k+2), 0.0) * zk
+		zk *= z2
+	}
+	return y
+}
+
+// polygamma returns the m-th derivative of the digamma function of z.
+func polygamma(m int, z complex128) complex128 {
+	if m == 0 {
+		return digamma(z)
+	} else if imag(z) == 0.0 && real(z) <= 0.0 && real(z) == math.Trunc(real(z)) {
+		return cmplx.NaN()
+	}
+
+	sign := 1.0 // (-1)^(m+1)
+	if m%2 == 0 {
+		sign = -1.0
+	}
+	fact := 1.0 // (m-1)!
+	for i := 2; i < m; i++ {
+		fact *= float64(i)
+	}
+
+	// recurrence: psi^(m)(z) = psi^(m)(z+1) + (-1)^(m+1) m! / z^(m+1)
+	var y complex128
+	for real(z) < 15.0+float64(m) {
+		y += complex(sign*fact*float64(m), 0.0) / ipow(z, m+1)
+		z += 1.0
+	}
+
+	// asymptotic expansion: psi^(m)(z) ~ (-1)^(m+1) [(m-1)!/z^m + m!/(2z^(m+1)) + sum B_2k (2k+m-1)!/((2k)! z^(2k+m))]
+	zm := ipow(z, m)
+	s := complex(fact, 0.0)/zm + complex(fact*float64(m)/2.0, 0.0)/(zm*z)
+	z2 := 1.0 / (z * z)
+	zk := z2 / zm
+	c := fact // (2k+m-1)!/(2k)!
+	for k, b := range bernoulli {
+		c *= float64(2*k+m) * float64(2*k+m+1) / float64(2*k+1) / float64(2*k+2)
+		s += complex(b*c, 0.0) * zk
+		zk *= z2
+	}
+	return y + complex(sign, 0.0)*s
+}
+
+// ipow returns z^n for a positive integer n by repeated squaring.
+func ipow(z complex128, n int) complex128 {
+	y := complex(1.0, 0.0)
+	for 0 < n {
+		if n&1 == 1 {
+			y *= z
+		}
+		z *= z
+		n >>= 1
+	}
+	return y
+}
+
+// isFactorialExact returns true if z! is an integer that a float64 represents exactly, which holds for the integers 0 through 22.
+func isFactorialExact(z complex128) bool {
+	x := real(z)
+	return imag(z) == 0.0 && 0.0 <= x && x == math.Trunc(x) && x <= 22.0
+}
+
+// factorial returns z! = gamma(z+1), which is calculated by multiplication for the integers 0 through 170 where it does not overflow.
+func factorial(z complex128) complex128 {
+	if x := real(z); imag(z) == 0.0 && 0.0 <= x && x == math.Trunc(x) && x < 171.0 {
+		y := 1.0
+		for i := 2.0; i <= real(z); i++ {
+			y *= i
+		}
+		return complex(y, 0.0)
+	}
+	return gamma(z + 1.0)
+}
+
+// floorMod returns the remainder of the floored division of x by y, which has the sign of y.
+func floorMod(x, y float64) float64 {
+	r := math.Mod(x, y)
+	if r != 0.0 && (r < 0.0) != (y < 0.0) {
+		r += y
+	}
+	return r
+}
@@ -0,0 +1,38 @@
+package formulae
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestSpecial(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(complex128) complex128
+		in   complex128
+		out  complex128
+	}{
+		{"cbrt", cbrt, -27, -3},
+		{"cbrt", cbrt, 8i, 1.7320508075688772 + 1i},
+		{"log2", log2, 1024, 10},
+		{"erf", erf, 1 + 1i, 1.3161512816979476 + 0.1904534692378347i},
+		{"erf", erf, -1 - 1i, -1.3161512816979476 - 0.1904534692378347i},
+		{"erf", erf, 3 + 0.5i, 1.0000280653614764 - 2.6284897222588233e-07i},
+		{"gamma", gamma, 5, 24},
+		{"gamma", gamma, 1 + 1i, 0.4980156681183560 - 0.1549498283018106i},
+		{"gamma", gamma, -0.5 + 1i, -0.46025215045075707 - 0.07056854203527457i},
+		{"digamma", digamma, 1, -0.5772156649015329},
+		{"digamma", digamma, 1 + 1i, 0.0946503206224770 + 1.0766740474685812i},
+		{"polygamma(1)", func(z complex128) complex128 { return polygamma(1, z) }, 1, 1.6449340668482264},
+		{"polygamma(2)", func(z complex128) complex128 { return polygamma(2, z) }, 1, -2.4041138063191885},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			y := test.f(test.in)
+			if cmplx.Abs(y-test.out) > Epsilon {
+				t.Fatal(y, "!=", test.out)
+			}
+		})
+	}
+}
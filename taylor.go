@@ -0,0 +1,127 @@
+package formulae
+
+import (
+	"fmt"
+	"math/cmplx"
+)
+
+// series holds the expansion point and remainder estimate of a Taylor series.
+type series struct {
+	at        complex128
+	order     int
+	rem       complex128 // first non-zero coefficient beyond the order
+	remDegree int
+}
+
+// Taylor returns the Taylor series of f in x around the point at up to and including the term of the given order. The coefficients are the derivatives of f evaluated at that point divided by their factorials. A series around zero is known as a Maclaurin series.
+func (f *Function) Taylor(at complex128, order int) (*Function, error) {
+	if order < 0 {
+		return nil, fmt.Errorf("order must be non-negative")
+	}
+
+	vars := scratchVars(f.Vars)
+	defer releaseVars(vars)
+	vars["x"] = at
+
+	// the two orders beyond the requested order give an estimate of the remainder, since either may vanish for odd or even functions
+	coefs := make([]complex128, order+3)
+	d := f.root
+	fact := 1.0
+	for k := range coefs {
+		if 0 < k {
+			d = Optimize(d.Derivative("x"))
+			fact *= float64(k)
+		}
+		y, err := calcAt(d, vars, at)
+		if err != nil {
+			return nil, fmt.Errorf("cannot expand '%v' at x=%v: %v", f.root, at, err)
+		} else if cmplx.IsNaN(y) || cmplx.IsInf(y) {
+			return nil, fmt.Errorf("cannot expand '%v' at x=%v: derivative %d is not finite", f.root, at, k)
+		}
+		coefs[k] = y / complex(fact, 0.0)
+	}
+
+	var root Node
+	for k, c := range coefs[:order+1] {
+		if c == 0.0 {
+			continue
+		}
+		neg := root != nil && imag(c) == 0.0 && real(c) < 0.0
+		if neg {
+			c = -c
+		}
+
+		var term Node
+		if 0 < k {
+			term = taylorBase(at)
+			if 1 < k {
+				term = &Expr{op: PowerOp, l: term, r: &Number{val: complex(float64(k), 0.0)}}
+			}
+			if c != 1.0 {
+				term = &Expr{op: MultiplyOp, l: &Number{val: c}, r: term}
+			}
+		} else {
+			term = &Number{val: c}
+		}
+
+		if root == nil {
+			root = term
+		} else if neg {
+			root = &Expr{op: SubtractOp, l: root, r: term}
+		} else {
+			root = &Expr{op: AddOp, l: root, r: term}
+		}
+	}
+	if root == nil {
+		root = ZeroNode
+	}
+
+	s := &series{
+		at:    at,
+		order: order,
+	}
+	for k := order + 1; k < len(coefs); k++ {
+		if coefs[k] != 0.0 {
+			s.rem = coefs[k]
+			s.remDegree = k
+			break
+		}
+	}
+	return &Function{
+		root:   root,
+		Vars:   f.Vars,
+		params: f.params,
+		series: s,
+	}, nil
+}
+
+// calcAt evaluates n at x, using real arithmetic for real x when possible to avoid round-off in the imaginary part.
+func calcAt(n Node, vars Vars, x complex128) (complex128, error) {
+	if imag(x) == 0.0 {
+		if y, err := n.CalcReal(vars); err == nil {
+			return complex(y, 0.0), nil
+		}
+	}
+	return n.Calc(vars)
+}
+
+// taylorBase returns x-at.
+func taylorBase(at complex128) Node {
+	x := &Variable{name: "x", pos: -1}
+	if at == 0.0 {
+		return x
+	} else if imag(at) == 0.0 && real(at) < 0.0 {
+		return &Expr{op: AddOp, l: x, r: &Number{val: -at}}
+	}
+	return &Expr{op: SubtractOp, l: x, r: &Number{val: at}}
+}
+
+// Remainder returns an estimate of the absolute error of the Taylor series f at x, which is the magnitude of the first non-vanishing term beyond its order. It returns an error if f is not a Taylor series.
+func (f *Function) Remainder(x complex128) (float64, error) {
+	if f.series == nil {
+		return 0.0, fmt.Errorf("function is not a Taylor series")
+	} else if f.series.remDegree == 0 {
+		return 0.0, nil
+	}
+	return cmplx.Abs(f.series.rem * ipow(x-f.series.at, f.series.remDegree)), nil
+}
@@ -0,0 +1,98 @@
+package formulae
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestTaylor(t *testing.T) {
+	tests := []struct {
+		in    string
+		at    complex128
+		order int
+		out   string
+	}{
+		{"e^x", 0, 3, "1+x+0.5*x^2+0.16666666666666666*x^3"},
+		{"sin(x)", 0, 4, "x-0.16666666666666666*x^3"},
+		{"cos(x)", 0, 2, "1-0.5*x^2"},
+		{"1/(1-x)", 0, 3, "1+x+x^2+x^3"},
+		{"ln(x)", 1, 2, "x-1-0.5*(x-1)^2"},
+		{"x^3+2x", -2, 5, "-12+14*(x+2)-6*(x+2)^2+(x+2)^3"},
+		{"x^2", 0, 1, "0"},
+		{"5", 1, 0, "5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			g, err := f.Taylor(test.at, test.order)
+			if err != nil {
+				t.Fatal(err)
+			} else if g.String() != test.out {
+				t.Fatal(g, "!=", test.out)
+			}
+		})
+	}
+}
+
+func TestTaylorRemainder(t *testing.T) {
+	f, _ := Parse("sin(x)")
+	for order := 1; order < 10; order += 2 {
+		g, err := f.Taylor(0, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, _ := g.Calc(0.5)
+		rem, err := g.Remainder(0.5)
+		if err != nil {
+			t.Fatal(err)
+		} else if diff := cmplx.Abs(y - complex(math.Sin(0.5), 0)); rem < diff || 2*diff < rem {
+			t.Fatal("remainder", rem, "does not estimate error", diff)
+		}
+	}
+
+	if _, err := f.Remainder(0.5); err == nil {
+		t.Fatal("expected error for function that is not a Taylor series")
+	}
+}
+
+func TestTaylorErr(t *testing.T) {
+	tests := []struct {
+		in    string
+		at    complex128
+		order int
+	}{
+		{"ln(x)", 0, 2},
+		{"1/(1-x)", 1, 2},
+		{"x", 0, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			f, errs := Parse(test.in)
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+			if _, err := f.Taylor(test.at, test.order); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestTaylorLaTeX(t *testing.T) {
+	f, _ := Parse("e^x")
+	g, _ := f.Taylor(0, 2)
+	if out := "f(x) = 1+x+0.5 x^{2}+O\\left(x^{3}\\right)"; g.LaTeX() != out {
+		t.Fatal(g.LaTeX(), "!=", out)
+	}
+
+	g, _ = f.Taylor(1, 1)
+	if out := "f(x) = 2.718281828459045+2.718281828459045 \\left(x-1\\right)+O\\left(\\left(x-1\\right)^{2}\\right)"; g.LaTeX() != out {
+		t.Fatal(g.LaTeX(), "!=", out)
+	}
+}